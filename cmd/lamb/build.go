@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/goat-framework/lamb/core/template"
+)
+
+// Runs lamb build
+//
+// Params:
+// - args ([]string): command arguments
+// - stdout (io.Writer): standard output
+// - stderr (io.Writer): standard error
+//
+// Returns:
+// - int: exit code
+//
+// Since: 0.2.0
+func runBuild(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	componentDir := flags.String("c", "", "component directory (default <src>/components)")
+	outDir := flags.String("o", "", "output directory (default .cache)")
+	prefixes := flags.String("p", "", "comma separated component prefixes (default ui-)")
+	allowUnknown := flags.Bool("allow-unknown", false, "keep unknown components as custom elements")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: lamb build <src> [-c components] [-o out] [-p ui-] [-allow-unknown]")
+		return 2
+	}
+
+	src := positional[0]
+	if *componentDir == "" {
+		*componentDir = defaultComponentDir(src)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		printErrors(stderr, err)
+		return 1
+	}
+
+	compiler := template.Compiler{
+		ComponentDir:           *componentDir,
+		OutputDir:              *outDir,
+		ComponentPrefixes:      splitPrefixes(*prefixes),
+		AllowUnknownComponents: *allowUnknown,
+		OnWrite: func(path string) {
+			fmt.Fprintf(stdout, "compiled %s\n", path)
+		},
+	}
+
+	if info.IsDir() {
+		err = compiler.CompileDir(src)
+	} else {
+		compiler.FilePath = src
+		compiler.SourceDir = filepath.Dir(src)
+		err = compiler.Compile()
+	}
+
+	if err != nil {
+		printErrors(stderr, err)
+		return 1
+	}
+
+	return 0
+}
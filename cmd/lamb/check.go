@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/goat-framework/lamb/core/template"
+)
+
+// Runs lamb check. Pages are compiled in memory so
+// unknown components are reported, components are
+// only parsed
+//
+// Params:
+// - args ([]string): command arguments
+// - stdout (io.Writer): standard output
+// - stderr (io.Writer): standard error
+//
+// Returns:
+// - int: exit code
+//
+// Since: 0.2.0
+func runCheck(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	componentDir := flags.String("c", "", "component directory (default <path>/components)")
+	prefixes := flags.String("p", "", "comma separated component prefixes (default ui-)")
+	allowUnknown := flags.Bool("allow-unknown", false, "keep unknown components as custom elements")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) == 0 {
+		positional = []string{"."}
+	}
+
+	var errs []error
+	for _, root := range positional {
+		options := template.Options{
+			ComponentDir:           *componentDir,
+			LayoutDir:              pageRoot(root),
+			PartialDir:             pageRoot(root),
+			ComponentPrefixes:      splitPrefixes(*prefixes),
+			AllowUnknownComponents: *allowUnknown,
+		}
+		if options.ComponentDir == "" {
+			options.ComponentDir = defaultComponentDir(root)
+		}
+
+		files, err := lambFiles(root)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, file := range files {
+			if err := checkFile(file, options); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		printErrors(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// Checks a single lamb file
+//
+// Params:
+// - file (string): path to the file
+// - options (template.Options): where components, layouts
+// and partials are resolved from
+//
+// Returns:
+// - error: if the file is invalid
+//
+// Since: 0.2.0
+func checkFile(file string, options template.Options) error {
+	if isInside(file, options.ComponentDir) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		_, err = template.ParseWithPrefixes(file, string(content), options.ComponentPrefixes)
+		return err
+	}
+
+	_, err := template.ParsePage(file, options)
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/goat-framework/lamb/core/template"
+)
+
+// Runs lamb fmt. Formatted files are printed unless
+// -w or -l is given
+//
+// Params:
+// - args ([]string): command arguments
+// - stdout (io.Writer): standard output
+// - stderr (io.Writer): standard error
+//
+// Returns:
+// - int: exit code
+//
+// Since: 0.2.0
+func runFmt(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the file")
+	list := flags.Bool("l", false, "list files whose formatting differs")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) == 0 {
+		positional = []string{"."}
+	}
+
+	var errs []error
+	for _, root := range positional {
+		files, err := lambFiles(root)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, file := range files {
+			if err := formatFile(file, *write, *list, stdout); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		printErrors(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// Formats a single lamb file
+//
+// Params:
+// - file (string): path to the file
+// - write (bool): write the result to the file
+// - list (bool): print the path when the formatting differs
+// - stdout (io.Writer): standard output
+//
+// Returns:
+// - error: if the file cannot be read, parsed or written
+//
+// Since: 0.2.0
+func formatFile(file string, write bool, list bool, stdout io.Writer) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := template.Format(string(content))
+	if err != nil {
+		var lambErr *template.Error
+		if errors.As(err, &lambErr) {
+			lambErr.File = file
+		}
+		return err
+	}
+
+	changed := formatted != string(content)
+
+	if list && changed {
+		fmt.Fprintln(stdout, file)
+	}
+	if write && changed {
+		return os.WriteFile(file, []byte(formatted), 0644)
+	}
+	if !write && !list {
+		fmt.Fprint(stdout, formatted)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+// Command lamb compiles, checks and formats lamb templates
+//
+// Usage:
+//
+//	lamb build <src> [-c components] [-o out] [-p ui-] [-allow-unknown]
+//	lamb check <path>... [-c components] [-p ui-] [-allow-unknown]
+//	lamb fmt <path>... [-w] [-l]
+//	lamb watch <src> [-c components] [-o out] [-p ui-] [-allow-unknown] [-interval 500ms]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goat-framework/lamb/core/template"
+)
+
+const usage = `Usage: lamb <command> [arguments]
+
+Commands:
+  build <src> [-c components] [-o out]   compile a lamb file or directory
+  check <path>... [-c components]        report errors without writing files
+  fmt <path>... [-w] [-l]                format lamb files
+  watch <src> [-c components] [-o out]   recompile pages when lamb files change
+
+build, check and watch also accept -p with comma separated component
+prefixes (default ui-) and -allow-unknown to keep unknown components
+as custom elements
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Runs the command line
+//
+// Params:
+// - args ([]string): arguments without the program name
+// - stdout (io.Writer): standard output
+// - stderr (io.Writer): standard error
+//
+// Returns:
+// - int: exit code, 1 on errors and 2 on bad usage
+//
+// Since: 0.2.0
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "build":
+		return runBuild(args[1:], stdout, stderr)
+	case "check":
+		return runCheck(args[1:], stdout, stderr)
+	case "fmt":
+		return runFmt(args[1:], stdout, stderr)
+	case "watch":
+		return runWatch(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "lamb: unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+// Parses flags that may appear before, between
+// or after positional arguments
+//
+// Params:
+// - flags (*flag.FlagSet): the flag set
+// - args ([]string): arguments to parse
+//
+// Returns:
+// - []string: positional arguments
+// - error: if a flag is invalid
+//
+// Since: 0.2.0
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// Lists the lamb files at a path, walking
+// directories recursively
+//
+// Params:
+// - root (string): file or directory
+//
+// Returns:
+// - []string: paths of the lamb files
+// - error: if the path cannot be read
+//
+// Since: 0.2.0
+func lambFiles(root string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".lamb.html") {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// Default component directory for a source path,
+// the components directory next to it
+//
+// Params:
+// - src (string): file or directory
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func defaultComponentDir(src string) string {
+	if info, err := os.Stat(src); err == nil && !info.IsDir() {
+		return filepath.Join(filepath.Dir(src), "components")
+	}
+
+	return filepath.Join(src, "components")
+}
+
+// Splits the value of the -p flag into
+// component tag prefixes
+// ex: ui-,acme- -> [ui- acme-]
+//
+// Params:
+// - value (string): comma separated prefixes
+//
+// Returns:
+// - []string: the prefixes, nil for the default
+//
+// Since: 0.2.0
+func splitPrefixes(value string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(value, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes
+}
+
+// Directory layouts and partials are resolved from
+// for a source path, the path itself or its directory
+//
+// Params:
+// - src (string): file or directory
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func pageRoot(src string) string {
+	if info, err := os.Stat(src); err == nil && !info.IsDir() {
+		return filepath.Dir(src)
+	}
+
+	return src
+}
+
+// Reports whether path is inside dir
+//
+// Params:
+// - path (string): the path
+// - dir (string): the directory
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isInside(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Prints errors, with source snippets for
+// positioned errors
+//
+// Params:
+// - w (io.Writer): output
+// - err (error): the error, possibly joined
+//
+// Since: 0.2.0
+func printErrors(w io.Writer, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			printErrors(w, e)
+		}
+		return
+	}
+
+	var lambErr *template.Error
+	if errors.As(err, &lambErr) {
+		fmt.Fprintln(w, lambErr.Detail())
+		return
+	}
+
+	fmt.Fprintln(w, err)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunBuild(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"views/home.lamb.html":             "<ui-title />",
+		"views/components/title.lamb.html": "<h1>{{ Title }}</h1>",
+	})
+	out := filepath.Join(dir, "out")
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"build", filepath.Join(dir, "views"), "-o", out}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, but got %d: %s", code, stderr.String())
+	}
+
+	result, err := os.ReadFile(filepath.Join(out, "home.html"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+	if string(result) != "<h1>{{ .Title }}</h1>" {
+		t.Errorf("Expected <h1>{{ .Title }}</h1>, but got %s", string(result))
+	}
+
+	expected := "compiled " + filepath.Join(out, "home.html") + "\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, stdout.String())
+	}
+}
+
+func TestRunBuildPrefixesAndAllowUnknown(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"views/home.lamb.html":             "<acme-title /><ui-card />",
+		"views/components/title.lamb.html": "<h1>{{ Title }}</h1>",
+	})
+	out := filepath.Join(dir, "out")
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"build", filepath.Join(dir, "views"), "-o", out, "-p", "acme-,ui-"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("Expected exit code 1, but got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "unknown component <ui-card>") {
+		t.Errorf("Expected output to contain %q, but got %q", "unknown component <ui-card>", stderr.String())
+	}
+
+	code = run([]string{"build", filepath.Join(dir, "views"), "-o", out, "-p", "acme-,ui-", "-allow-unknown"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, but got %d: %s", code, stderr.String())
+	}
+
+	result, err := os.ReadFile(filepath.Join(out, "home.html"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+	if string(result) != "<h1>{{ .Title }}</h1><ui-card />" {
+		t.Errorf("Expected <h1>{{ .Title }}</h1><ui-card />, but got %s", string(result))
+	}
+}
+
+func TestRunCheckPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"home.lamb.html":             "<acme-title />",
+		"components/title.lamb.html": "<acme-icon><h1>{{ Title }}</h1></ui-icon>",
+		"components/icon.lamb.html":  "<i><slot /></i>",
+	})
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"check", dir, "-p", "acme-"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("Expected exit code 1, but got %d", code)
+	}
+
+	message := filepath.Join(dir, "components", "title.lamb.html") + ":1:1: unclosed <acme-icon>"
+	if !strings.Contains(stderr.String(), message) {
+		t.Errorf("Expected output to contain %q, but got %q", message, stderr.String())
+	}
+}
+
+func TestRunCheckReportsPositionedErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"home.lamb.html":             "<p>\n  <ui-missing />\n</p>",
+		"components/card.lamb.html":  "@if Open\n<div></div>",
+		"components/title.lamb.html": "<h1>{{ Title }}</h1>",
+	})
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"check", dir}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("Expected exit code 1, but got %d", code)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "home.lamb.html") + ":2:3: unknown component <ui-missing>",
+		filepath.Join(dir, "components", "card.lamb.html") + ":1:1: unclosed @if",
+	}
+	for _, message := range expected {
+		if !strings.Contains(stderr.String(), message) {
+			t.Errorf("Expected output to contain %q, but got %q", message, stderr.String())
+		}
+	}
+}
+
+func TestRunFmt(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"clean.lamb.html": "<p>{{ Name }}</p>\n",
+		"messy.lamb.html": "<p>{{Name}}</p>  \n",
+	})
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"fmt", "-l", dir}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, but got %d: %s", code, stderr.String())
+	}
+	if stdout.String() != filepath.Join(dir, "messy.lamb.html")+"\n" {
+		t.Errorf("Expected only messy.lamb.html to be listed, but got %q", stdout.String())
+	}
+
+	code = run([]string{"fmt", "-w", dir}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, but got %d: %s", code, stderr.String())
+	}
+
+	result, _ := os.ReadFile(filepath.Join(dir, "messy.lamb.html"))
+	if string(result) != "<p>{{ Name }}</p>\n" {
+		t.Errorf("Expected the file to be formatted, but got %q", string(result))
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := run([]string{"serve"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2, but got %d", code)
+	}
+}
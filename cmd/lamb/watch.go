@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/goat-framework/lamb/core/template"
+)
+
+// Runs lamb watch until interrupted
+//
+// Params:
+// - args ([]string): command arguments
+// - stdout (io.Writer): standard output
+// - stderr (io.Writer): standard error
+//
+// Returns:
+// - int: exit code
+//
+// Since: 0.2.0
+func runWatch(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	componentDir := flags.String("c", "", "component directory (default <src>/components)")
+	outDir := flags.String("o", "", "output directory (default .cache)")
+	prefixes := flags.String("p", "", "comma separated component prefixes (default ui-)")
+	allowUnknown := flags.Bool("allow-unknown", false, "keep unknown components as custom elements")
+	interval := flags.Duration("interval", 500*time.Millisecond, "time between checks for changes")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "usage: lamb watch <src> [-c components] [-o out] [-p ui-] [-allow-unknown] [-interval 500ms]")
+		return 2
+	}
+
+	src := positional[0]
+	if *componentDir == "" {
+		*componentDir = defaultComponentDir(src)
+	}
+
+	watcher := &template.Watcher{
+		SrcDir:                 src,
+		ComponentDir:           *componentDir,
+		ComponentPrefixes:      splitPrefixes(*prefixes),
+		AllowUnknownComponents: *allowUnknown,
+		OutputDir:              *outDir,
+		Interval:               *interval,
+		OnCompile: func(page string, err error) {
+			if err != nil {
+				printErrors(stderr, err)
+				return
+			}
+			fmt.Fprintf(stdout, "compiled %s\n", page)
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(stdout, "watching %s\n", src)
+	err = watcher.Watch(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		printErrors(stderr, err)
+		return 1
+	}
+
+	return 0
+}
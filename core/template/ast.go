@@ -0,0 +1,383 @@
+package template
+
+import (
+	"strings"
+)
+
+// Byte offset of a node within the source
+// of its document
+//
+// Since: 0.2.0
+type Pos int
+
+// Returns the position itself so that any
+// node embedding Pos satisfies the Node interface
+//
+// Receiver:
+// - p (Pos)
+//
+// Returns:
+// - Pos: the position
+//
+// Since: 0.2.0
+func (p Pos) Position() Pos {
+	return p
+}
+
+// An element of the lamb syntax tree
+//
+// Since: 0.2.0
+type Node interface {
+	Position() Pos
+}
+
+// A parsed lamb file
+//
+// Fields:
+// - Name (string): name or path of the file
+// - Source (string): the original source
+// - Nodes ([]Node): top level nodes
+// - Props (*PropsNode): props declared by the file, nil when missing
+// - Extends (*ExtendsNode): layout the file extends, nil when missing
+//
+// Since: 0.2.0
+type Document struct {
+	Name    string
+	Source  string
+	Nodes   []Node
+	Props   *PropsNode
+	Extends *ExtendsNode
+}
+
+// Converts a position into a line and column,
+// both starting at 1
+//
+// Receiver:
+// - d (*Document)
+//
+// Params:
+// - pos (Pos): position in the source
+//
+// Returns:
+// - int: line number
+// - int: column number
+//
+// Since: 0.2.0
+func (d *Document) LineColumn(pos Pos) (int, int) {
+	offset := int(pos)
+	if offset > len(d.Source) {
+		offset = len(d.Source)
+	}
+
+	before := d.Source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+
+	return line, column
+}
+
+// Plain text copied to the output as is
+//
+// Fields:
+// - Text (string): the text
+// - RawText (bool): text of an html comment or a raw text
+// element such as <script>, where lamb syntax is not processed
+//
+// Since: 0.2.0
+type TextNode struct {
+	Pos
+	Text    string
+	RawText bool
+}
+
+// Text that is not processed, from @@ or a
+// @verbatim block
+//
+// Fields:
+// - Text (string): text to output
+// - Raw (string): the source
+//
+// Since: 0.2.0
+type VerbatimNode struct {
+	Pos
+	Text string
+	Raw  string
+}
+
+// A {{ }} interpolation
+//
+// Fields:
+// - Expr (string): the text between the braces
+// - Raw (string): the full source of the interpolation
+//
+// Since: 0.2.0
+type ExpressionNode struct {
+	Pos
+	Expr string
+	Raw  string
+}
+
+// Kind of an item inside a start tag
+//
+// Since: 0.2.0
+type AttributeKind int
+
+const (
+	// A regular name="value" attribute
+	AttributeNormal AttributeKind = iota
+	// An @attributes(...) directive
+	AttributeDirective
+	// A {{ }} interpolation between attributes
+	AttributeExpression
+	// A :name="expression" attribute bound to template data
+	AttributeBinding
+)
+
+// An item inside a start tag
+//
+// Fields:
+// - Kind (AttributeKind): kind of the item
+// - Name (string): attribute name, without the colon of a binding
+// - Value (string): attribute value, directive arguments,
+// interpolation body or bound expression
+// - HasValue (bool): attribute has a value
+// - Eq (string): source between the name and the value
+// - Quote (string): quote around the value, if any
+// - Space (string): whitespace before the item
+//
+// Since: 0.2.0
+type Attribute struct {
+	Pos
+	Kind     AttributeKind
+	Name     string
+	Value    string
+	HasValue bool
+	Eq       string
+	Quote    string
+	Space    string
+}
+
+// A html element
+//
+// Fields:
+// - Name (string): tag name
+// - Attrs ([]*Attribute): items of the start tag
+// - Space (string): whitespace before the end of the start tag
+// - SelfClosing (bool): start tag ends with />
+// - Closed (bool): element has an end tag
+// - Children ([]Node): content of the element
+// - TagEnd (Pos): offset just past the start tag
+// - ClosePos (Pos): offset of the end tag
+// - End (Pos): offset just past the element
+//
+// Since: 0.2.0
+type ElementNode struct {
+	Pos
+	Name        string
+	Attrs       []*Attribute
+	Space       string
+	SelfClosing bool
+	Closed      bool
+	Children    []Node
+	TagEnd      Pos
+	ClosePos    Pos
+	End         Pos
+}
+
+// A <ui-*> component
+//
+// Fields:
+// - Component (string): component name without the prefix
+//
+// Since: 0.2.0
+type ComponentNode struct {
+	ElementNode
+	Component string
+}
+
+// A single branch of a directive block
+//
+// Fields:
+// - Cond (string): branch arguments
+// - Parens (bool): arguments were written in parentheses
+// - Body ([]Node): content of the branch
+//
+// Since: 0.2.0
+type Branch struct {
+	Pos
+	Cond   string
+	Parens bool
+	Body   []Node
+}
+
+// An @if / @elseif / @else block
+//
+// Fields:
+// - Branches ([]*Branch): @if and @elseif branches
+// - Else (*Branch): @else branch, nil when missing
+//
+// Since: 0.2.0
+type IfNode struct {
+	Pos
+	Branches []*Branch
+	Else     *Branch
+}
+
+// An @for block
+//
+// Fields:
+// - Key (string): index or key variable, empty when not named
+// - Value (string): loop variable
+// - Collection (string): expression to range over
+// - Parens (bool): arguments were written in parentheses
+// - Body ([]Node): content of the loop
+// - Empty (*Branch): @empty branch, nil when missing
+//
+// Since: 0.2.0
+type ForNode struct {
+	Pos
+	Key        string
+	Value      string
+	Collection string
+	Parens     bool
+	Body       []Node
+	Empty      *Branch
+}
+
+// An @switch block
+//
+// Fields:
+// - Value (string): expression being switched on
+// - Parens (bool): arguments were written in parentheses
+// - Lead (string): whitespace before the first @case
+// - Cases ([]*Branch): @case branches, Cond holds the
+// comma separated values
+// - Default (*Branch): @default branch, nil when missing
+//
+// Since: 0.2.0
+type SwitchNode struct {
+	Pos
+	Value   string
+	Parens  bool
+	Lead    string
+	Cases   []*Branch
+	Default *Branch
+}
+
+// An @with block
+//
+// Fields:
+// - Value (string): expression that becomes the data
+// - Parens (bool): arguments were written in parentheses
+// - Body ([]Node): content rendered when the value is not empty
+// - Else (*Branch): @else branch, nil when missing
+//
+// Since: 0.2.0
+type WithNode struct {
+	Pos
+	Value  string
+	Parens bool
+	Body   []Node
+	Else   *Branch
+}
+
+// An @let declaration
+//
+// Fields:
+// - Name (string): variable name
+// - Value (string): expression assigned to the variable
+// - Parens (bool): arguments were written in parentheses
+//
+// Since: 0.2.0
+type LetNode struct {
+	Pos
+	Name   string
+	Value  string
+	Parens bool
+}
+
+// An @extends("layout") declaration
+//
+// Fields:
+// - Layout (string): name of the layout
+//
+// Since: 0.2.0
+type ExtendsNode struct {
+	Pos
+	Layout string
+}
+
+// An @section("name") block filling a
+// region of the extended layout
+//
+// Fields:
+// - Name (string): name of the section
+// - Body ([]Node): content of the section
+//
+// Since: 0.2.0
+type SectionNode struct {
+	Pos
+	Name string
+	Body []Node
+}
+
+// An @yield("name", "default") placeholder
+// in a layout
+//
+// Fields:
+// - Name (string): name of the section to show
+// - Default (string): shown when no page fills the section
+//
+// Since: 0.2.0
+type YieldNode struct {
+	Pos
+	Name    string
+	Default string
+}
+
+// An @include("name", Data) of a partial, rendered
+// with its data set to the given expression
+//
+// Fields:
+// - Name (string): partial name relative to the partial directory
+// - Data (string): expression the partial reads from, empty for
+// the current data
+//
+// Since: 0.2.0
+type IncludeNode struct {
+	Pos
+	Name string
+	Data string
+}
+
+// A prop declared by a component
+//
+// Fields:
+// - Name (string): prop name
+// - Type (string): string, int, float, bool or enum
+// - Options ([]string): values allowed by an enum
+// - Default (string): value used when the caller omits the prop
+// - HasDefault (bool): prop has a default and is optional
+//
+// Since: 0.2.0
+type Prop struct {
+	Pos
+	Name       string
+	Type       string
+	Options    []string
+	Default    string
+	HasDefault bool
+}
+
+// An @props(...) declaration
+//
+// Fields:
+// - Args (string): the declaration as written
+// - Props ([]*Prop): declared props in order
+//
+// Since: 0.2.0
+type PropsNode struct {
+	Pos
+	Args  string
+	Props []*Prop
+}
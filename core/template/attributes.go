@@ -2,7 +2,7 @@ package template
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -13,47 +13,51 @@ import (
 // Since: 0.1.0
 type Attributes map[string]string
 
-// Get the attributes from the specified element
+// Starts the value of a boolean attribute bound to an
+// expression, the rest of the value is the condition
+// ex: "\x00.Checked"
+//
+// Since: 0.2.0
+const boundBoolean = "\x00"
+
+// Finds the value of an attribute in a start tag
 //
 // Params:
-// - element (string): html element
+// - attrs ([]*Attribute): items of the start tag
+// - name (string): attribute name
 //
 // Returns:
-// - map[string]string: attribute key value pairs
+// - string: the value, empty when missing
 //
-// Since: 0.1.0
-func getAttributes(element string) Attributes {
-	attributes := make(Attributes)
-
-	element = getRootElement(element)
-
-	regex := regexp.MustCompile(`(\w+)="([^"]*)"`)
-	matches := regex.FindAllStringSubmatch(element, -1)
-
-	for _, match := range matches {
-		key := match[1]
-		value := match[2]
-		attributes[key] = value
+// Since: 0.2.0
+func attributeValue(attrs []*Attribute, name string) string {
+	for _, attr := range attrs {
+		if attr.Kind == AttributeNormal && attr.Name == name {
+			return attr.Value
+		}
 	}
 
-	return attributes
+	return ""
 }
 
-// Gets the root UI element tag
+// Reports whether a start tag has an attribute
 //
 // Params:
-// - element (string): element to pull tag from
+// - attrs ([]*Attribute): items of the start tag
+// - name (string): attribute name
 //
 // Returns:
-// - string: the first ui tag
+// - bool
 //
-// Since: 0.1.0
-func getRootElement(element string) string {
-	regex := regexp.MustCompile(`<ui-[\w-]+(?:\s[^>]*)?/?>`)
-
-	match := regex.FindString(element)
+// Since: 0.2.0
+func hasAttribute(attrs []*Attribute, name string) bool {
+	for _, attr := range attrs {
+		if attr.Kind == AttributeNormal && attr.Name == name {
+			return true
+		}
+	}
 
-	return match
+	return false
 }
 
 // Merges another Attributes map into the current one.
@@ -72,7 +76,7 @@ func getRootElement(element string) string {
 // Since: 0.1.0
 func (a Attributes) mergeAttributes(other Attributes) Attributes {
 	for key, value := range other {
-		if key == "class" {
+		if key == "class" && a[key] != "" && value != "" {
 			a[key] = a[key] + " " + value
 		} else {
 			a[key] = value
@@ -82,40 +86,78 @@ func (a Attributes) mergeAttributes(other Attributes) Attributes {
 	return a
 }
 
-// Convert Attribute map to html string
+// Adds the attributes of defaults the map does not
+// have yet. Classes are combined, the current ones first
 //
 // Receiver:
-// - a (Attributes): the attributes map
+// - a (Attributes): current attributes map
+//
+// Params:
+// - defaults (Attributes): attributes used when missing
 //
 // Returns:
-// - string: html string
-// ex: class="btn"
+// - Attributes: combined attributes map
 //
-// Since: 0.1.0
-func (a Attributes) toString() string {
-	parts := make([]string, 0, len(a))
+// Since: 0.2.0
+func (a Attributes) withDefaults(defaults Attributes) Attributes {
+	for key, value := range defaults {
+		current, ok := a[key]
+		switch {
+		case !ok || (key == "class" && current == ""):
+			a[key] = value
+		case key == "class" && value != "":
+			a[key] = current + " " + value
+		}
+	}
+
+	return a
+}
+
+// Copies the attributes map
+//
+// Receiver:
+// - a (Attributes): attributes to copy
+//
+// Returns:
+// - Attributes: the copy
+//
+// Since: 0.2.0
+func (a Attributes) clone() Attributes {
+	copied := make(Attributes, len(a))
 	for key, value := range a {
-		parts = append(parts, fmt.Sprintf(`%s="%s"`, key, value))
+		copied[key] = value
 	}
-	return strings.Join(parts, " ")
+
+	return copied
 }
 
-// Extracts the @attributes map from content
+// Convert Attribute map to html string. Bound boolean
+// attributes are only written when their condition holds
 //
-// Params:
-// - content (string): the content
+// Receiver:
+// - a (Attributes): the attributes map
 //
 // Returns:
-// - string: html attribute string
+// - string: html string
+// ex: class="btn" {{ if .On }}checked{{ end }}
 //
 // Since: 0.1.0
-func extractAttributesString(content string) string {
-	regex := regexp.MustCompile(`@attributes\(([^)]+)\)`)
-	matches := regex.FindStringSubmatch(content)
-	if len(matches) > 1 {
-		return matches[1]
+func (a Attributes) toString() string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
 	}
-	return ""
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(a))
+	for _, key := range keys {
+		if cond, ok := strings.CutPrefix(a[key], boundBoolean); ok {
+			parts = append(parts, "{{ if "+cond+" }}"+key+"{{ end }}")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, key, a[key]))
+	}
+	return strings.Join(parts, " ")
 }
 
 // Convert html @attribute string to Attributes map
@@ -128,43 +170,85 @@ func extractAttributesString(content string) string {
 //
 // Since: 0.1.0
 func parseAttributesString(attributesString string) Attributes {
-	attributePairs := strings.Split(attributesString, ",")
+	attributes, _ := parseAttributesDirective(attributesString)
+	return attributes
+}
+
+// Parse the arguments of an @attributes directive,
+// a comma separated list of "key": "value" pairs
+//
+// Params:
+// - attributesString (string): directive arguments
+//
+// Returns:
+// - Attributes: the pairs parsed before any error
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func parseAttributesDirective(attributesString string) (Attributes, error) {
 	attributes := make(Attributes)
+	rest := strings.TrimSpace(attributesString)
 
-	for _, pair := range attributePairs {
-		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
+	for rest != "" {
+		key, after, ok := cutAttributeWord(rest, ":,")
+		if !ok || key == "" {
+			return attributes, fmt.Errorf("expected a key at %q", rest)
+		}
+
+		after = strings.TrimSpace(after)
+		if !strings.HasPrefix(after, ":") {
+			return attributes, fmt.Errorf("expected ':' after %q", key)
+		}
 
-			key = strings.Trim(key, `"`)
-			value = strings.Trim(value, `"`)
-			attributes[key] = value
+		value, after, ok := cutAttributeWord(strings.TrimSpace(after[1:]), ",")
+		if !ok {
+			return attributes, fmt.Errorf("expected a value for %q", key)
+		}
+		attributes[key] = value
+
+		rest = strings.TrimSpace(after)
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return attributes, fmt.Errorf("expected ',' after the value of %q", key)
 		}
+		rest = strings.TrimSpace(rest[1:])
 	}
 
-	return attributes
+	return attributes, nil
 }
 
-// Pass parent attributes to @attributes directive in
-// the child component
+// Cuts a quoted string or a bare word from
+// the start of an @attributes argument list
 //
 // Params:
-// - content (string): the content to parse
-// - parentAttributes (Attributes): parent attributes map
+// - s (string): the arguments
+// - stops (string): characters ending a bare word
 //
 // Returns:
-// - string: html element with parent attributes mapped
-// to the child component @attribute directive
-//
-// Since: 0.1.0
-func applyAttributesDirective(content string, parentAttributes Attributes) string {
-	regex := regexp.MustCompile(`@attributes\(([^)]+)\)`)
-
-	return regex.ReplaceAllStringFunc(content, func(match string) string {
-		attributesString := extractAttributesString(match)
-		childAttributes := parseAttributesString(attributesString)
-		mergedAttributes := parentAttributes.mergeAttributes(childAttributes)
-		return mergedAttributes.toString()
-	})
+// - string: the word without quotes
+// - string: the remaining arguments
+// - bool: whether a word was found
+//
+// Since: 0.2.0
+func cutAttributeWord(s string, stops string) (string, string, bool) {
+	if s == "" {
+		return "", s, false
+	}
+
+	if s[0] == '"' || s[0] == '\'' {
+		end := skipQuoted(s, 0)
+		if end < 0 {
+			return "", s, false
+		}
+		return s[1:end], s[end+1:], true
+	}
+
+	end := strings.IndexAny(s, stops)
+	if end < 0 {
+		end = len(s)
+	}
+	word := strings.TrimSpace(s[:end])
+	return word, s[end:], word != ""
 }
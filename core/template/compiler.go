@@ -1,8 +1,11 @@
 package template
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -13,11 +16,41 @@ import (
 // - ComponentDir (string): path to directory
 // containing components
 // - FilePath (string): path to file to compile
+// - PageFS (fs.FS): file system FilePath is read from,
+// the disk is used when nil
+// - ComponentFS (fs.FS): file system ComponentDir is read from,
+// the disk is used when nil
+// - OutputDir (string): directory compiled files are written to,
+// defaults to .cache in the working directory
+// - SourceDir (string): directory whose layout is mirrored in
+// OutputDir, defaults to the working directory
+// - LayoutDir (string): directory @extends resolves layouts from,
+// defaults to SourceDir or the directory of FilePath
+// - PartialDir (string): directory @include resolves partials from,
+// defaults to SourceDir or the directory of FilePath
+// - ComponentPrefixes ([]string): tag prefixes of the components
+// in ComponentDir, defaults to ui-
+// - ComponentRoots ([]ComponentRoot): more places components are
+// searched, in order, after ComponentDir
+// - AllowUnknownComponents (bool): leave components without a file
+// in the output as custom elements instead of failing
+// - OnWrite (func(string)): called with the path of each compiled file
+// once it is written, may be nil
 //
 // Since: 0.1.0
 type Compiler struct {
-	ComponentDir string
-	FilePath     string
+	ComponentDir           string
+	FilePath               string
+	PageFS                 fs.FS
+	ComponentFS            fs.FS
+	OutputDir              string
+	SourceDir              string
+	LayoutDir              string
+	PartialDir             string
+	ComponentPrefixes      []string
+	ComponentRoots         []ComponentRoot
+	AllowUnknownComponents bool
+	OnWrite                func(path string)
 }
 
 // Compile the lamb file and components into a parsable
@@ -45,6 +78,122 @@ func Compile(filePath string, componentDir string) error {
 	return nil
 }
 
+// Compile every lamb file in a directory tree, mirroring
+// its layout in the output directory. The component
+// directory is skipped
+//
+// Params:
+// - srcDir (string): directory to compile
+// - componentDir (string): path to directory of lamb components
+// - outDir (string): directory to write to, defaults to .cache
+//
+// Returns:
+// - error: every failing file joined with errors.Join
+//
+// Since: 0.2.0
+func CompileDir(srcDir string, componentDir string, outDir string) error {
+	compiler := Compiler{ComponentDir: componentDir, OutputDir: outDir}
+	return compiler.CompileDir(srcDir)
+}
+
+// Compile every lamb file in a directory tree with the
+// settings of the compiler, mirroring its layout in the
+// output directory. The tree is read from PageFS when set.
+// FilePath and SourceDir are set for each file, the
+// component directory is skipped
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Params:
+// - srcDir (string): directory to compile
+//
+// Returns:
+// - error: every failing file joined with errors.Join
+//
+// Since: 0.2.0
+func (c *Compiler) CompileDir(srcDir string) error {
+	componentDir := c.ComponentDir
+	var errs []error
+
+	walk := func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+
+		if d.IsDir() {
+			if componentDir == "" {
+				return nil
+			}
+			if (c.PageFS != nil && path.Clean(file) == path.Clean(componentDir)) ||
+				(c.PageFS == nil && sameDir(file, componentDir)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(file, ".lamb.html") {
+			return nil
+		}
+
+		compiler := *c
+		compiler.FilePath = file
+		compiler.SourceDir = srcDir
+		if err := compiler.Compile(); err != nil {
+			errs = append(errs, err)
+		}
+
+		return nil
+	}
+
+	var err error
+	if c.PageFS != nil {
+		err = fs.WalkDir(c.PageFS, srcDir, walk)
+	} else {
+		err = filepath.WalkDir(srcDir, walk)
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reports whether two paths point to the same directory
+//
+// Params:
+// - a (string): first path
+// - b (string): second path
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func sameDir(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+
+	return absA == absB
+}
+
+// Compile the configured lamb file into the
+// output directory
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func (c *Compiler) Compile() error {
+	return c.compileLamb()
+}
+
 // Creates the .cache directory in the
 // root of the library
 //
@@ -66,10 +215,50 @@ func (c *Compiler) createCache() (string, error) {
 		}
 	}
 
-	fmt.Printf("created the cache directory at %s\n", cacheDir)
 	return cacheDir, nil
 }
 
+// Creates the output directory, falling back
+// to the .cache directory when none is set
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string: path to the output directory
+// - error
+//
+// Since: 0.2.0
+func (c *Compiler) createOutputDir() (string, error) {
+	if c.OutputDir == "" {
+		return c.createCache()
+	}
+
+	err := os.MkdirAll(c.OutputDir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return c.OutputDir, nil
+}
+
+// Path of the output directory, without creating it
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (c *Compiler) getOutputDir() string {
+	if c.OutputDir == "" {
+		return filepath.Join(getLibraryRoot(), ".cache")
+	}
+
+	return c.OutputDir
+}
+
 // Creates the output file name
 //
 // Receiver:
@@ -83,6 +272,48 @@ func (c *Compiler) getOutputFileName() string {
 	return strings.TrimSuffix(filepath.Base(c.FilePath), ".lamb.html") + ".html"
 }
 
+// Creates the output path relative to the output
+// directory, mirroring the location of the file
+// inside the source directory. Files outside of
+// it, and every file when neither SourceDir nor
+// OutputDir is set, are written to the root of
+// the output directory
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string: the relative output path
+// ex: users/profile.html
+//
+// Since: 0.2.0
+func (c *Compiler) getOutputRelPath() string {
+	fileName := c.getOutputFileName()
+	dir := filepath.Dir(c.FilePath)
+
+	// Without either directory the file keeps its 0.1.0 location
+	if c.SourceDir == "" && c.OutputDir == "" {
+		return fileName
+	}
+
+	if c.SourceDir != "" {
+		rel, err := filepath.Rel(c.SourceDir, dir)
+		if err != nil {
+			return fileName
+		}
+		dir = rel
+	} else if filepath.IsAbs(dir) {
+		return fileName
+	}
+
+	dir = filepath.Clean(dir)
+	if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return fileName
+	}
+
+	return filepath.Join(dir, fileName)
+}
+
 // Creates the output file path
 //
 // Receiver:
@@ -116,7 +347,6 @@ func writeFileToCache(content string, path string) error {
 		return fmt.Errorf("failed to write compiled file: %w", err)
 	}
 
-	fmt.Printf("Compiled file written to %s\n", path)
 	return nil
 }
 
@@ -130,26 +360,123 @@ func writeFileToCache(content string, path string) error {
 //
 // Since: 0.1.0
 func (c *Compiler) compileLamb() error {
+	_, err := c.compile()
+	return err
+}
+
+// Gets the directory layouts are resolved from
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (c *Compiler) getLayoutDir() string {
+	if c.LayoutDir != "" {
+		return c.LayoutDir
+	}
+
+	return c.getPageRoot()
+}
+
+// Gets the directory partials are resolved from
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (c *Compiler) getPartialDir() string {
+	if c.PartialDir != "" {
+		return c.PartialDir
+	}
+
+	return c.getPageRoot()
+}
+
+// Gets the directory layouts and partials default to,
+// SourceDir or the directory of FilePath
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (c *Compiler) getPageRoot() string {
+	switch {
+	case c.SourceDir != "":
+		return c.SourceDir
+	case c.PageFS != nil:
+		return path.Dir(c.FilePath)
+	}
+
+	return filepath.Dir(c.FilePath)
+}
+
+// Compiles the specified file to the output directory
+// and reports the components, layouts and partials it depends on
+//
+// Receiver:
+// - c (*Compiler)
+//
+// Returns:
+// - []string: paths of the components, layouts and partials the file uses,
+// known even when compilation fails
+// - error
+//
+// Since: 0.2.0
+func (c *Compiler) compile() ([]string, error) {
 	// Parse the file to get the content
-	parsedContent, err := ParseLamb(c.FilePath, c.ComponentDir)
+	g := newGenerator(Options{
+		ComponentDir:           c.ComponentDir,
+		LayoutDir:              c.getLayoutDir(),
+		PartialDir:             c.getPartialDir(),
+		PageFS:                 c.PageFS,
+		ComponentFS:            c.ComponentFS,
+		ComponentPrefixes:      c.ComponentPrefixes,
+		ComponentRoots:         c.ComponentRoots,
+		AllowUnknownComponents: c.AllowUnknownComponents,
+	})
+
+	doc, err := g.loadPage(c.FilePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cachePath, err := c.createCache()
+	parsedContent, err := g.generate(doc)
 	if err != nil {
-		return err
+		return g.dependencies, err
+	}
+
+	outputDir, err := c.createOutputDir()
+	if err != nil {
+		return g.dependencies, err
 	}
 
-	// Remove the ".lamb" from the filename and replace it with ".html"
-	outputFileName := c.getOutputFileName()
+	// Replace ".lamb.html" with ".html", keeping the subdirectories
+	outputFileName := c.getOutputRelPath()
 
 	// Define the path for the compiled .html file
-	outputFilePath := c.getOutputFilePath(outputFileName, cachePath)
+	outputFilePath := c.getOutputFilePath(outputFileName, outputDir)
+
+	err = os.MkdirAll(filepath.Dir(outputFilePath), os.ModePerm)
+	if err != nil {
+		return g.dependencies, fmt.Errorf("failed to create output directory: %w", err)
+	}
 
 	err = writeFileToCache(parsedContent, outputFilePath)
 	if err != nil {
-		return err
+		return g.dependencies, err
 	}
-	return nil
+
+	if c.OnWrite != nil {
+		c.OnWrite(outputFilePath)
+	}
+	return g.dependencies, nil
 }
@@ -2,8 +2,12 @@ package template
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestGetOutputFileName(t *testing.T) {
@@ -36,3 +40,157 @@ func TestGetOutputFilePath(t *testing.T) {
 		t.Errorf("Expected %v, but got %v", expected, result)
 	}
 }
+
+func TestGetOutputRelPath(t *testing.T) {
+	examples := []struct {
+		compiler Compiler
+		expected string
+	}{
+		{Compiler{FilePath: "views/users/profile.lamb.html"}, "profile.html"},
+		{Compiler{FilePath: "views/users/profile.lamb.html", OutputDir: "out"}, filepath.Join("views", "users", "profile.html")},
+		{Compiler{FilePath: "./example.lamb.html", OutputDir: "out"}, "example.html"},
+		{Compiler{FilePath: "../../tests/example.lamb.html", OutputDir: "out"}, "example.html"},
+		{Compiler{FilePath: "views/users/profile.lamb.html", SourceDir: "views"}, filepath.Join("users", "profile.html")},
+		{Compiler{FilePath: "other/profile.lamb.html", SourceDir: "views"}, "profile.html"},
+	}
+
+	for _, example := range examples {
+		result := example.compiler.getOutputRelPath()
+		if result != example.expected {
+			t.Errorf("Expected %v, but got %v", example.expected, result)
+		}
+	}
+}
+
+func TestCompileToOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/users/profile.lamb.html":     "<ui-avatar />",
+		"views/components/avatar.lamb.html": `<img src="{{ Avatar }}" />`,
+	})
+
+	compiler := Compiler{
+		FilePath:     filepath.Join(dir, "views", "users", "profile.lamb.html"),
+		ComponentDir: filepath.Join(dir, "views", "components"),
+		SourceDir:    filepath.Join(dir, "views"),
+		OutputDir:    filepath.Join(dir, "out", "html"),
+	}
+
+	var written []string
+	compiler.OnWrite = func(path string) {
+		written = append(written, path)
+	}
+
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if len(written) != 1 || written[0] != filepath.Join(dir, "out", "html", "users", "profile.html") {
+		t.Errorf("Expected OnWrite to be called with the output path, but got %v", written)
+	}
+
+	result, err := os.ReadFile(filepath.Join(dir, "out", "html", "users", "profile.html"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<img src="{{ .Avatar }}" />`
+	if string(result) != expected {
+		t.Errorf("Expected %v, but got %v", expected, string(result))
+	}
+}
+
+func TestCompileDir(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/home.lamb.html":             "<ui-title />",
+		"views/users/profile.lamb.html":    "<p>{{ Name }}</p>",
+		"views/users/notes.txt":            "not a template",
+		"views/components/title.lamb.html": "<h1>{{ Title }}</h1>",
+	})
+	out := filepath.Join(dir, "out")
+
+	err := CompileDir(filepath.Join(dir, "views"), filepath.Join(dir, "views", "components"), out)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := map[string]string{
+		"home.html":          "<h1>{{ .Title }}</h1>",
+		"users/profile.html": "<p>{{ .Name }}</p>",
+	}
+	for name, content := range expected {
+		result, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Errorf("Expected %s to be written, but got error: %s", name, err.Error())
+		} else if string(result) != content {
+			t.Errorf("Expected %v, but got %v", content, string(result))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "components")); !os.IsNotExist(err) {
+		t.Errorf("Expected the component directory to be skipped")
+	}
+}
+
+func TestCompileDirFromFS(t *testing.T) {
+	pages := fstest.MapFS{
+		"views/home.lamb.html":              {Data: []byte("@extends(\"layouts/base\")\n@section(\"content\")<ui-title />@end")},
+		"views/users/profile.lamb.html":     {Data: []byte("<p>{{ Name }}</p>")},
+		"views/layouts/base.lamb.html":      {Data: []byte("<main>@yield(\"content\")</main>")},
+		"views/components/title.lamb.html":  {Data: []byte("<h1>{{ Title }}</h1>")},
+		"views/components/broken.lamb.html": {Data: []byte("<ui-missing />")},
+	}
+	out := t.TempDir()
+
+	compiler := Compiler{
+		PageFS:       pages,
+		ComponentFS:  pages,
+		ComponentDir: "views/components",
+		OutputDir:    out,
+	}
+	if err := compiler.CompileDir("views"); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := map[string]string{
+		"home.html":          "<main><h1>{{ .Title }}</h1></main>",
+		"users/profile.html": "<p>{{ .Name }}</p>",
+	}
+	for name, content := range expected {
+		result, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Errorf("Expected %s to be written, but got error: %s", name, err.Error())
+		} else if string(result) != content {
+			t.Errorf("Expected %v, but got %v", content, string(result))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "components")); !os.IsNotExist(err) {
+		t.Errorf("Expected the component directory to be skipped")
+	}
+}
+
+func TestCompileDirReportsEveryFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/a.lamb.html": "<ui-missing />",
+		"views/b.lamb.html": "@if Open\n<p>open</p>",
+		"views/c.lamb.html": "<p>fine</p>",
+	})
+
+	err := CompileDir(filepath.Join(dir, "views"), filepath.Join(dir, "components"), filepath.Join(dir, "out"))
+	if err == nil {
+		t.Fatalf("Expected an error, but got none")
+	}
+
+	for _, name := range []string{"a.lamb.html", "b.lamb.html"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected the error to mention %s, but got %s", name, err.Error())
+		}
+	}
+
+	if _, statErr := os.Stat(filepath.Join(dir, "out", "c.html")); statErr != nil {
+		t.Errorf("Expected c.html to be compiled despite the other failures")
+	}
+}
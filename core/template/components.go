@@ -1,203 +1,169 @@
 package template
 
 import (
-	"fmt"
-	"regexp"
+	"path"
+	"sort"
+	"strings"
 )
 
-// Represents a self closing component
-//
-// Fields:
-// - ComponentName (string): component name
-// ex: link
-// - ComponentFilePath (string): component filepath
-// ex: components/link.lamb.html
-// - Element (string): base element
-// ex: <ui-link />
-//
-// Since: 0.1.0
-type SelfClosingUIComponent struct {
-	ComponentName     string
-	ComponentFilePath string
-	Element           string
-	Attributes        *Attributes
-}
-
-// Represents a wrapped component
-//
-// Fields:
-// - ComponentName (string): component name
-// ex: button
-// - ComponentFilePath (string): component filepath
-// ex: components/button.lamb.html
-// - InnerContent (string): component inner content
-// ex: Submit
-// - Element (string): base element
-// ex: <ui-button>Submit</ui-button>
-//
-// Since: 0.1.0
-type WrappedUIComponent struct {
-	ComponentName     string
-	ComponentFilePath string
-	InnerContent      string
-	Element           string
-	Attributes        *Attributes
-	ClosingTag        string
-}
-
-// Gets all of the self closing components
+// Prefix of component tag names
 //
-// Params:
-// - content (string): content to parse
-//
-// Returns:
-// []SelfClosingUIComponents: list of self closing components
-//
-// Since: 0.1.0
-func getSelfClosingUIComponents(content string, componentDir string) []SelfClosingUIComponent {
-	elements := findSelfClosingUIElements(content)
-	names := createUIComponentNames(elements)
-	paths := createUIComponentFilePaths(componentDir, names)
-
-	var structs []SelfClosingUIComponent
-
-	for i := range elements {
-		attributes := getAttributes(elements[i])
-		structs = append(structs, SelfClosingUIComponent{
-			ComponentName:     names[i],
-			ComponentFilePath: paths[i],
-			Element:           elements[i],
-			Attributes:        &attributes,
-		})
-	}
+// Since: 0.2.0
+const componentPrefix = "ui-"
 
-	return structs
-}
+// Turns the namespaces of a component name into
+// directories, they are separated by dots or colons
+// ex: <ui-forms.input>, <ui-forms:input>
+//
+// Since: 0.2.0
+var namespaceReplacer = strings.NewReplacer(".", "/", ":", "/")
 
-// Gets all of the wrapped components
+// Creates the file path of a single component.
+// Namespaces of the name are directories
+// ex: forms.input -> components/forms/input.lamb.html
 //
 // Params:
-// - content (string): content to parse
+// - baseDir (string): directory containing components
+// - name (string): component name
 //
 // Returns:
-// - []WrappedUIComponent: list of wrapped components
-//
-// Since: 0.1.0
-func getWrappedUIComponents(content string, componentDir string) []WrappedUIComponent {
-	elements := findWrappedUIElements(content)
-	names := createUIComponentNames(elements)
-	contents := getComponentInnerContent(elements)
-	paths := createUIComponentFilePaths(componentDir, names)
-
-	var structs []WrappedUIComponent
-
-	for i := range elements {
-		attributes := getAttributes(elements[i])
-		structs = append(structs, WrappedUIComponent{
-			ComponentName:     names[i],
-			ComponentFilePath: paths[i],
-			InnerContent:      contents[i],
-			Element:           elements[i],
-			Attributes:        &attributes,
-		})
-	}
-
-	return structs
+// - string: filepath to the component
+//
+// Since: 0.2.0
+func componentFilePath(baseDir string, name string) string {
+	return path.Join(baseDir, namespaceReplacer.Replace(name)+".lamb.html")
 }
 
-// Looks for self closing element syntax
+// Creates the file paths a component is looked up at,
+// in order. A directory component is read from its
+// index file
+// ex: forms -> components/forms.lamb.html, components/forms/index.lamb.html
 //
 // Params:
-// - content (string): content to parse
+// - baseDir (string): directory containing components
+// - name (string): component name
 //
 // Returns:
-// - []string: list of elements
+// - []string: candidate filepaths
 //
-// Since: 0.1.0
-func findSelfClosingUIElements(content string) []string {
-	regex := regexp.MustCompile(`<ui-[\w-]+\s*[^>]*\/>`)
-	matches := regex.FindAllString(content, -1)
-	return matches
+// Since: 0.2.0
+func componentFilePaths(baseDir string, name string) []string {
+	file := componentFilePath(baseDir, name)
+	index := path.Join(strings.TrimSuffix(file, ".lamb.html"), "index.lamb.html")
+
+	return []string{file, index}
 }
 
-// Looks for wrapped element syntax
+// Reports whether every namespace of a
+// component name is non empty
+// ex: forms.input is valid, forms..input is not
 //
 // Params:
-// - content (string): content to parse
+// - name (string): component name
 //
 // Returns:
-// - []string: list of elements
+// - bool
 //
-// Since: 0.1.0
-func findWrappedUIElements(content string) []string {
-	regex := regexp.MustCompile(`(?s)<ui-[\w-]+\b[^/>]*>.*?</ui-[\w-]+>`)
-	matches := regex.FindAllString(content, -1)
-	return matches
+// Since: 0.2.0
+func isValidComponentName(name string) bool {
+	for _, part := range strings.Split(namespaceReplacer.Replace(name), "/") {
+		if part == "" {
+			return false
+		}
+	}
+
+	return true
 }
 
-// Grabs component's inner content
-// Must be a wrapped component
+// Picks the names closest to a misspelled one
+// by edit distance, closest first
 //
 // Params:
-// - elements ([]string): list of elements
+// - name (string): the misspelled name
+// - names ([]string): known names
+// - limit (int): maximum number of names returned
 //
 // Returns:
-// []string: list of inner contents
-//
-// Since: 0.1.0
-func getComponentInnerContent(elements []string) []string {
-	var contents []string
-	regex := regexp.MustCompile(`(?s)<ui-[\w-]+[^>]*>(.*?)</ui-[\w-]+>`)
-
-	for _, element := range elements {
-		match := regex.FindStringSubmatch(element)
-		if len(match) > 1 {
-			contents = append(contents, match[1])
-		} else {
-			contents = append(contents, "")
+// - []string: names within a third of the length of name
+//
+// Since: 0.2.0
+func closestNames(name string, names []string, limit int) []string {
+	maxDistance := max(len(name)/3, 1)
+
+	distances := make(map[string]int)
+	var closest []string
+	for _, candidate := range names {
+		if d := editDistance(name, candidate); d <= maxDistance && candidate != name {
+			distances[candidate] = d
+			closest = append(closest, candidate)
 		}
 	}
 
-	return contents
+	sort.Slice(closest, func(i, j int) bool {
+		if distances[closest[i]] != distances[closest[j]] {
+			return distances[closest[i]] < distances[closest[j]]
+		}
+		return closest[i] < closest[j]
+	})
+
+	if len(closest) > limit {
+		closest = closest[:limit]
+	}
+	return closest
 }
 
-// Grabs component names
+// Counts the single character insertions, deletions
+// and substitutions turning one string into another
 //
 // Params:
-// - elements ([]string): list of elements
+// - a (string): first string
+// - b (string): second string
 //
 // Returns:
-// []string: list of names
-//
-// Since: 0.1.0
-func createUIComponentNames(elements []string) []string {
-	var names []string
-	regex := regexp.MustCompile(`<ui-([\w-]+)`)
+// - int: the Levenshtein distance
+//
+// Since: 0.2.0
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
 
-	for _, element := range elements {
-		match := regex.FindStringSubmatch(element)
-		if len(match) > 1 {
-			names = append(names, match[1])
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
 		}
+		previous, current = current, previous
 	}
 
-	return names
+	return previous[len(b)]
 }
 
-// Creates the component file paths
+// Joins tag names for a message
+// ex: <ui-form>, <ui-font> or <ui-forms>
 //
 // Params:
-// - names ([]string): list of component names
+// - tags ([]string): tag names
 //
 // Returns:
-// - []string: list of filepaths to components
-//
-// Since: 0.1.0
-func createUIComponentFilePaths(baseDir string, names []string) []string {
-	var paths []string
-	for _, name := range names {
-		path := fmt.Sprintf("%s/%s.lamb.html", baseDir, name)
-		paths = append(paths, path)
+// - string
+//
+// Since: 0.2.0
+func joinOr(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = "<" + tag + ">"
 	}
-	return paths
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
 }
@@ -0,0 +1,188 @@
+package template
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Configures an Engine
+//
+// Fields:
+// - PageDir (string): directory containing pages
+// - ComponentDir (string): directory containing components
+// - LayoutDir (string): directory @extends resolves layouts from,
+// read like pages. Defaults to PageDir
+// - PartialDir (string): directory @include resolves partials from,
+// read like pages. Defaults to PageDir
+// - PageFS (fs.FS): file system pages are read from,
+// PageDir is relative to it. Pages are read from disk when nil
+// - ComponentFS (fs.FS): file system components are read from,
+// ComponentDir is relative to it. Components are read from disk when nil
+// - ComponentPrefixes ([]string): tag prefixes of the components
+// in ComponentDir, defaults to ui-. Tags with any other prefix
+// are left as they are
+// - ComponentRoots ([]ComponentRoot): more places components are
+// searched, in order, after ComponentDir
+// - AllowUnknownComponents (bool): leave components without a file
+// in the output as custom elements instead of failing
+// - Funcs (htmltemplate.FuncMap): functions available to templates
+//
+// Since: 0.2.0
+type Options struct {
+	PageDir                string
+	ComponentDir           string
+	LayoutDir              string
+	PartialDir             string
+	PageFS                 fs.FS
+	ComponentFS            fs.FS
+	ComponentPrefixes      []string
+	ComponentRoots         []ComponentRoot
+	AllowUnknownComponents bool
+	Funcs                  htmltemplate.FuncMap
+}
+
+// A place components are read from, such as
+// a component pack shipped in an embed.FS
+//
+// Fields:
+// - Prefix (string): tag prefix of its components, defaults to ui-
+// ex: acme- for <acme-button>
+// - Dir (string): directory containing the components
+// - FS (fs.FS): file system Dir is read from,
+// components are read from disk when nil
+//
+// Since: 0.2.0
+type ComponentRoot struct {
+	Prefix string
+	Dir    string
+	FS     fs.FS
+}
+
+// Compiles lamb pages in memory and keeps the
+// resulting templates ready for rendering
+//
+// Fields:
+// - options (Options): engine configuration
+// - mu (sync.Mutex): guards templates
+// - templates (map[string]*htmltemplate.Template): loaded templates by name
+//
+// Since: 0.2.0
+type Engine struct {
+	options   Options
+	mu        sync.Mutex
+	templates map[string]*htmltemplate.Template
+}
+
+// Creates an engine
+//
+// Params:
+// - options (Options): engine configuration
+//
+// Returns:
+// - *Engine
+//
+// Since: 0.2.0
+func New(options Options) *Engine {
+	return &Engine{
+		options:   options,
+		templates: make(map[string]*htmltemplate.Template),
+	}
+}
+
+// Compiles a page and parses it with html/template.
+// Templates are compiled once and reused afterwards
+//
+// Receiver:
+// - e (*Engine)
+//
+// Params:
+// - name (string): page path relative to the page directory,
+// the .lamb.html extension is optional
+// ex: users/profile
+//
+// Returns:
+// - *htmltemplate.Template: the parsed template
+// - error: if the page cannot be compiled or parsed
+//
+// Since: 0.2.0
+func (e *Engine) Load(name string) (*htmltemplate.Template, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if tmpl, ok := e.templates[name]; ok {
+		return tmpl, nil
+	}
+
+	g := newGenerator(e.options)
+
+	doc, err := g.loadPage(e.pagePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := g.generate(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := htmltemplate.New(name).Funcs(e.options.Funcs).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compiled template %s: %w", name, err)
+	}
+
+	e.templates[name] = tmpl
+	return tmpl, nil
+}
+
+// Renders a page with the given data
+//
+// Receiver:
+// - e (*Engine)
+//
+// Params:
+// - w (io.Writer): output
+// - name (string): page name, see Load
+// - data (any): template data
+//
+// Returns:
+// - error: if the page cannot be loaded or executed
+//
+// Since: 0.2.0
+func (e *Engine) Render(w io.Writer, name string, data any) error {
+	tmpl, err := e.Load(name)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, data)
+}
+
+// Creates the path of a page
+//
+// Receiver:
+// - e (*Engine)
+//
+// Params:
+// - name (string): page name
+//
+// Returns:
+// - string: path to the lamb file
+//
+// Since: 0.2.0
+func (e *Engine) pagePath(name string) string {
+	if !strings.HasSuffix(name, ".lamb.html") {
+		name += ".lamb.html"
+	}
+
+	if e.options.PageFS != nil {
+		return path.Join(e.options.PageDir, name)
+	}
+
+	return filepath.Join(e.options.PageDir, name)
+}
@@ -0,0 +1,352 @@
+package template
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEngineRender(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"pages/home.lamb.html":        "<ui-button>{{ Label }}</ui-button>",
+		"components/button.lamb.html": `<button @attributes("class": "btn")><slot /></button>`,
+	})
+
+	engine := New(Options{
+		PageDir:      filepath.Join(dir, "pages"),
+		ComponentDir: filepath.Join(dir, "components"),
+	})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]string{"Label": "<Save>"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<button class="btn">&lt;Save&gt;</button>`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but got %v", expected, b.String())
+	}
+}
+
+func TestEngineLoadCachesTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{"home.lamb.html": "<p>home</p>"})
+
+	engine := New(Options{PageDir: dir})
+
+	first, err := engine.Load("home.lamb.html")
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+	second, _ := engine.Load("home.lamb.html")
+
+	if first != second {
+		t.Errorf("Expected the same template to be returned")
+	}
+}
+
+func TestEngineLoadMissingPage(t *testing.T) {
+	engine := New(Options{PageDir: t.TempDir()})
+
+	if _, err := engine.Load("missing"); err == nil {
+		t.Errorf("Expected an error, but got none")
+	}
+}
+
+func TestEngineRenderFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/home.lamb.html":            {Data: []byte("<ui-card>{{ Title }}</ui-card>")},
+		"views/components/card.lamb.html": {Data: []byte(`<div class="card"><slot /></div>`)},
+	}
+
+	engine := New(Options{
+		PageDir:      "views",
+		ComponentDir: "views/components",
+		PageFS:       fsys,
+		ComponentFS:  fsys,
+	})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]string{"Title": "Hello"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="card">Hello</div>`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but got %v", expected, b.String())
+	}
+}
+
+func TestEngineRenderSlotInsideWith(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/home.lamb.html":            {Data: []byte("<ui-list>{{ Title }}</ui-list>")},
+		"views/components/list.lamb.html": {Data: []byte("<ul>@for(item in Items)<li>{{ item }}: <slot /></li>@end</ul>")},
+	}
+
+	engine := New(Options{
+		PageDir:      "views",
+		ComponentDir: "views/components",
+		PageFS:       fsys,
+		ComponentFS:  fsys,
+	})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]any{"Title": "Hello", "Items": []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<ul><li>a: Hello</li><li>b: Hello</li></ul>`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but got %v", expected, b.String())
+	}
+}
+
+func TestEngineRenderRawText(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.lamb.html": {Data: []byte("<!-- {{ x }} --><textarea>{{ y }}</textarea><p>{{ Title }}</p>")},
+	}
+
+	engine := New(Options{PageFS: fsys})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]string{"Title": "Hello"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<textarea>{{ y }}</textarea><p>Hello</p>`
+	if b.String() != expected {
+		t.Errorf("Expected %v, but got %v", expected, b.String())
+	}
+}
+
+func TestEngineRenderStyleAndScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.lamb.html": {Data: []byte("<style>.a{{ color: red }}</style><script>const t = `{{ x }}`; if (a) {{ b }}</script><script lamb>const title = {{ Title }}</script>")},
+	}
+
+	engine := New(Options{PageFS: fsys})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]string{"Title": "Hello"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<style>.a{{ color: red }}</style><script>const t = `{{ x }}`; if (a) {{ b }}</script><script>const title = \"Hello\"</script>"
+	if b.String() != expected {
+		t.Errorf("Expected %v, but got %v", expected, b.String())
+	}
+}
+
+func TestEngineRenderExpressions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.lamb.html": {Data: []byte("@if Count > 3 and not User.Admin\n{{ Count | double }} {{ len(Items) }}\n@else\nnone\n@end")},
+	}
+
+	engine := New(Options{
+		PageFS: fsys,
+		Funcs:  map[string]any{"double": func(n int) int { return n * 2 }},
+	})
+
+	data := map[string]any{
+		"Count": 5,
+		"Items": []string{"a", "b"},
+		"User":  map[string]bool{"Admin": false},
+	}
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", data); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "\n10 2\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestEngineRenderWithAndLet(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.lamb.html":             {Data: []byte(`@let total = Order.Total` + "\n" + `<ui-badge label="{{ Order.ID }}" />`)},
+		"components/badge.lamb.html": {Data: []byte("@props(label: string)\n@with Order.Customer\n<b>{{ label }}</b> {{ Name }}\n@end")},
+	}
+
+	engine := New(Options{PageFS: fsys, ComponentFS: fsys, ComponentDir: "components"})
+
+	data := map[string]any{
+		"Order": map[string]any{"ID": "A1", "Total": 3, "Customer": map[string]string{"Name": "Ann"}},
+	}
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", data); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "\n\n<b>A1</b> Ann\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestEngineRenderLayoutFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/home.lamb.html":        {Data: []byte("@extends(\"app\")\n@section(\"main\")\n<p>{{ Title }}</p>\n@end")},
+		"views/layouts/app.lamb.html": {Data: []byte(`<title>@yield("title", "Lamb")</title><main>@yield("main")</main>`)},
+	}
+
+	engine := New(Options{
+		PageDir:   "views",
+		LayoutDir: "views/layouts",
+		PageFS:    fsys,
+	})
+
+	var b strings.Builder
+	err := engine.Render(&b, "home", map[string]string{"Title": "Hello"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<title>Lamb</title><main>\n<p>Hello</p>\n</main>"
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestEngineRenderInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/home.lamb.html":           {Data: []byte(`<ul class="@include('partials/theme', Theme)">@include("partials/items", Menu)</ul>`)},
+		"views/partials/items.lamb.html": {Data: []byte(`@for(item in .)<li>{{ item }}</li>@end`)},
+		"views/partials/theme.lamb.html": {Data: []byte(`menu-{{ . }}`)},
+	}
+
+	engine := New(Options{
+		PageDir:    "views",
+		PartialDir: "views",
+		PageFS:     fsys,
+	})
+
+	data := map[string]any{"Theme": "dark", "Menu": []string{"Home", "<About>"}}
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", data); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<ul class="menu-dark"><li>Home</li><li>&lt;About&gt;</li></ul>`
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestEngineComponentRoots(t *testing.T) {
+	pages := fstest.MapFS{
+		"home.lamb.html": {Data: []byte(`<ui-button>Save</ui-button><ui-card /><acme-button>Buy</acme-button>`)},
+	}
+	kit := fstest.MapFS{
+		"kit/button.lamb.html": {Data: []byte(`<button class="kit"><slot /></button>`)},
+		"kit/card.lamb.html":   {Data: []byte(`<div class="kit-card"></div>`)},
+	}
+	vendor := fstest.MapFS{
+		"button.lamb.html": {Data: []byte(`<button class="acme"><slot /></button>`)},
+	}
+
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"button.lamb.html": `<button class="app"><slot /></button>`,
+	})
+
+	engine := New(Options{
+		ComponentDir: dir,
+		PageFS:       pages,
+		ComponentRoots: []ComponentRoot{
+			{Dir: "kit", FS: kit},
+			{Prefix: "acme-", FS: vendor},
+		},
+	})
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", nil); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<button class="app">Save</button><div class="kit-card"></div><button class="acme">Buy</button>`
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+}
+
+func TestEngineComponentRootPrefixes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"home.lamb.html":        {Data: []byte(`<ui-button /><acme-button />`)},
+		"missing.lamb.html":     {Data: []byte(`<ui-button /><acme-missing />`)},
+		"acme/button.lamb.html": {Data: []byte(`<button></button>`)},
+	}
+
+	engine := New(Options{
+		PageFS:         fsys,
+		ComponentRoots: []ComponentRoot{{Prefix: "acme-", Dir: "acme", FS: fsys}},
+	})
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", nil); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<ui-button /><button></button>`
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+
+	message := "missing.lamb.html:1:14: unknown component <acme-missing>, acme/missing.lamb.html does not exist"
+	if _, err := engine.Load("missing"); err == nil || !strings.Contains(err.Error(), message) {
+		t.Errorf("Expected error containing %q, but got %v", message, err)
+	}
+}
+
+func TestEngineComponentRootOverrideWrapsVendor(t *testing.T) {
+	app := fstest.MapFS{
+		"home.lamb.html":   {Data: []byte(`<ui-button>Save</ui-button>`)},
+		"button.lamb.html": {Data: []byte(`<acme-button class="app"><slot /></acme-button>`)},
+	}
+	vendor := fstest.MapFS{
+		"button.lamb.html": {Data: []byte(`<button @attributes()><slot /></button>`)},
+	}
+
+	engine := New(Options{
+		PageFS: app,
+		ComponentRoots: []ComponentRoot{
+			{Prefix: "ui-", Dir: ".", FS: app},
+			{Prefix: "acme-", Dir: ".", FS: vendor},
+		},
+	})
+
+	var b strings.Builder
+	if err := engine.Render(&b, "home", nil); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<button class="app">Save</button>`
+	if b.String() != expected {
+		t.Errorf("Expected %q, but got %q", expected, b.String())
+	}
+
+	vendor["button.lamb.html"] = &fstest.MapFile{Data: []byte(`<ui-button />`)}
+	engine = New(Options{
+		PageFS: app,
+		ComponentRoots: []ComponentRoot{
+			{Prefix: "ui-", Dir: ".", FS: app},
+			{Prefix: "acme-", Dir: ".", FS: vendor},
+		},
+	})
+
+	message := "component <ui-button> includes itself (included from home.lamb.html -> button.lamb.html (ui-))"
+	if _, err := engine.Load("home"); err == nil || !strings.Contains(err.Error(), message) {
+		t.Errorf("Expected error containing %q, but got %v", message, err)
+	}
+}
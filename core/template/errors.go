@@ -0,0 +1,139 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A compile error pointing at a position in a lamb file
+//
+// Fields:
+// - File (string): file containing the problem
+// - Line (int): line number, starting at 1
+// - Column (int): column number, starting at 1
+// - Snippet (string): the offending source line
+// - Chain ([]string): files from the page down to File
+// ex: page.lamb.html -> layout.lamb.html -> button.lamb.html
+// - Message (string): description of the problem
+// - Err (error): underlying error, if any
+//
+// Since: 0.2.0
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Snippet string
+	Chain   []string
+	Message string
+	Err     error
+}
+
+// Formats the error on a single line
+//
+// Receiver:
+// - e (*Error)
+//
+// Returns:
+// - string
+// ex: layout.lamb.html:3:5: unknown component <ui-foo> (included from page.lamb.html)
+//
+// Since: 0.2.0
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+
+	if len(e.Chain) > 1 {
+		message += fmt.Sprintf(" (included from %s)", strings.Join(e.Chain[:len(e.Chain)-1], " -> "))
+	}
+
+	return message
+}
+
+// Formats the error with the offending line
+// and a marker under the column
+//
+// Receiver:
+// - e (*Error)
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (e *Error) Detail() string {
+	var b strings.Builder
+
+	b.WriteString(e.Error())
+	if e.Snippet != "" {
+		fmt.Fprintf(&b, "\n    %s\n    %s^", e.Snippet, strings.Repeat(" ", max(e.Column-1, 0)))
+	}
+
+	return b.String()
+}
+
+// Returns the underlying error
+//
+// Receiver:
+// - e (*Error)
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Builds an error pointing at a position in the document
+//
+// Receiver:
+// - d (*Document)
+//
+// Params:
+// - pos (Pos): position of the problem
+// - format (string): message format
+// - args (...any): message arguments
+//
+// Returns:
+// - error: the *Error
+//
+// Since: 0.2.0
+func (d *Document) errorf(pos Pos, format string, args ...any) error {
+	line, column := d.LineColumn(pos)
+
+	offset := min(int(pos), len(d.Source))
+	start := strings.LastIndex(d.Source[:offset], "\n") + 1
+	end := strings.IndexByte(d.Source[offset:], '\n')
+	if end < 0 {
+		end = len(d.Source)
+	} else {
+		end += offset
+	}
+
+	return &Error{
+		File:    d.Name,
+		Line:    line,
+		Column:  column,
+		Snippet: strings.TrimRight(d.Source[start:end], "\r"),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Records the include chain on an error
+// that does not have one yet
+//
+// Params:
+// - err (error): the error
+// - chain ([]string): files from the page down to the failing file
+//
+// Returns:
+// - error: the same error
+//
+// Since: 0.2.0
+func withChain(err error, chain []string) error {
+	var lambErr *Error
+	if errors.As(err, &lambErr) && lambErr.Chain == nil {
+		lambErr.Chain = append([]string{}, chain...)
+	}
+
+	return err
+}
@@ -0,0 +1,98 @@
+package template
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeLambFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestErrorUnknownComponentInChain(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":              "<ui-layout>Hello</ui-layout>",
+		"components/layout.lamb.html": "<main>\n  <ui-missing />\n  <slot />\n</main>",
+	})
+
+	page := filepath.Join(dir, "page.lamb.html")
+	layout := filepath.Join(dir, "components", "layout.lamb.html")
+
+	_, err := ParseLamb(page, filepath.Join(dir, "components"))
+
+	var lambErr *Error
+	if !errors.As(err, &lambErr) {
+		t.Fatalf("Expected a *Error, but got %v", err)
+	}
+
+	if lambErr.File != layout || lambErr.Line != 2 || lambErr.Column != 3 {
+		t.Errorf("Expected %s:2:3, but got %s:%d:%d", layout, lambErr.File, lambErr.Line, lambErr.Column)
+	}
+
+	expectedChain := []string{page, layout}
+	if !reflect.DeepEqual(lambErr.Chain, expectedChain) {
+		t.Errorf("Expected %v, but got %v", expectedChain, lambErr.Chain)
+	}
+
+	if lambErr.Snippet != "  <ui-missing />" {
+		t.Errorf("Expected snippet '  <ui-missing />', but got '%s'", lambErr.Snippet)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the error to wrap fs.ErrNotExist")
+	}
+}
+
+func TestErrorUnclosedIfInComponent(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":            "<ui-card />",
+		"components/card.lamb.html": "<div>\n@if Open\n</div>",
+	})
+
+	_, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+
+	var lambErr *Error
+	if !errors.As(err, &lambErr) {
+		t.Fatalf("Expected a *Error, but got %v", err)
+	}
+
+	if lambErr.Line != 2 || lambErr.Message != "unclosed @if, missing @end" {
+		t.Errorf("Expected an unclosed @if on line 2, but got %s", lambErr.Error())
+	}
+
+	if len(lambErr.Chain) != 2 {
+		t.Errorf("Expected a chain of two files, but got %v", lambErr.Chain)
+	}
+}
+
+func TestErrorDetail(t *testing.T) {
+	err := &Error{
+		File:    "page.lamb.html",
+		Line:    1,
+		Column:  5,
+		Snippet: "<p>{{ title</p>",
+		Message: "unclosed {{",
+	}
+
+	expected := "page.lamb.html:1:5: unclosed {{\n    <p>{{ title</p>\n        ^"
+
+	if err.Detail() != expected {
+		t.Errorf("Expected %q, but got %q", expected, err.Detail())
+	}
+}
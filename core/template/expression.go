@@ -0,0 +1,762 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Functions every go template knows about
+//
+// Since: 0.2.0
+var builtinFuncs = map[string]bool{
+	"and":      true,
+	"call":     true,
+	"eq":       true,
+	"ge":       true,
+	"gt":       true,
+	"html":     true,
+	"index":    true,
+	"js":       true,
+	"le":       true,
+	"len":      true,
+	"lt":       true,
+	"ne":       true,
+	"not":      true,
+	"or":       true,
+	"print":    true,
+	"printf":   true,
+	"println":  true,
+	"slice":    true,
+	"urlquery": true,
+}
+
+// Go template keywords. Actions starting with one
+// of them are plain go template and kept as they are
+//
+// Since: 0.2.0
+var templateKeywords = map[string]bool{
+	"block":    true,
+	"break":    true,
+	"continue": true,
+	"define":   true,
+	"else":     true,
+	"end":      true,
+	"if":       true,
+	"range":    true,
+	"template": true,
+	"with":     true,
+}
+
+// Infix comparison operators and the
+// go template function they map to
+//
+// Since: 0.2.0
+var comparisonFuncs = map[string]string{
+	"==": "eq",
+	"!=": "ne",
+	"<":  "lt",
+	"<=": "le",
+	">":  "gt",
+	">=": "ge",
+}
+
+// Kind of an expression token
+//
+// Since: 0.2.0
+type exprTokenType int
+
+const (
+	exprIdent exprTokenType = iota
+	exprField
+	exprVariable
+	exprLiteral
+	exprOperator
+	exprPipe
+	exprLeftParen
+	exprRightParen
+	exprComma
+)
+
+// A token of a lamb expression
+//
+// Fields:
+// - typ (exprTokenType): kind of the token
+// - val (string): source of the token
+// - spaced (bool): token is preceded by whitespace
+//
+// Since: 0.2.0
+type exprToken struct {
+	typ    exprTokenType
+	val    string
+	spaced bool
+}
+
+// Splits a lamb expression into tokens
+//
+// Params:
+// - expr (string): the expression
+//
+// Returns:
+// - []exprToken: the tokens
+// - error: if the expression contains unknown characters
+//
+// Since: 0.2.0
+func lexExpr(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+
+	for i := 0; i < len(expr); {
+		start := skipSpace(expr, i)
+		spaced := start > i
+		i = start
+		if i >= len(expr) {
+			break
+		}
+
+		c := expr[i]
+		t := exprToken{spaced: spaced}
+		end := i + 1
+
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			close := skipQuoted(expr, i)
+			if close < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			t.typ, end = exprLiteral, close+1
+		case c == '-' && i+1 < len(expr) && isDigit(expr[i+1]), isDigit(c):
+			end = scanWord(expr, i+1)
+			t.typ = exprLiteral
+		case c == '.':
+			t.typ, end = exprField, max(scanPath(expr, i), i+1)
+		case c == '$':
+			t.typ, end = exprVariable, scanPath(expr, i+1)
+		case isLetter(c):
+			end = scanPath(expr, i)
+			t.typ = exprIdent
+			switch expr[i:end] {
+			case "true", "false", "nil":
+				t.typ = exprLiteral
+			}
+		case c == '(':
+			t.typ = exprLeftParen
+		case c == ')':
+			t.typ = exprRightParen
+		case c == ',':
+			t.typ = exprComma
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="),
+			strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			t.typ, end = exprOperator, i+2
+		case c == '<' || c == '>' || c == '!':
+			t.typ = exprOperator
+		case c == '|':
+			t.typ = exprPipe
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+
+		t.val = expr[i:end]
+		tokens = append(tokens, t)
+		i = end
+	}
+
+	return tokens, nil
+}
+
+// Translates lamb expressions to go template pipelines.
+// Bare names are read from the data, infix operators
+// become function calls
+// ex: Count > 3 and not User.Admin -> and (gt .Count 3) (not .User.Admin)
+//
+// Fields:
+// - tokens ([]exprToken): tokens of the expression
+// - i (int): index of the current token
+// - scope (*scope): names bound while rendering
+//
+// Since: 0.2.0
+type exprParser struct {
+	tokens []exprToken
+	i      int
+	scope  *scope
+}
+
+// Translates a lamb expression to a go template pipeline
+//
+// Params:
+// - expr (string): the expression
+// - s (*scope): names bound while rendering, may be nil
+//
+// Returns:
+// - string: the pipeline
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func translatePipeline(expr string, s *scope) (string, error) {
+	tokens, err := lexExpr(expr)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("empty expression")
+	}
+
+	p := &exprParser{tokens: tokens, scope: s}
+	pipeline, err := p.parsePipeline()
+	if err != nil {
+		return "", err
+	}
+	if t, ok := p.peek(); ok {
+		return "", fmt.Errorf("unexpected %s", t.val)
+	}
+
+	return pipeline, nil
+}
+
+// Translates a lamb expression to a single go
+// template operand, parenthesized when needed
+//
+// Params:
+// - expr (string): the expression
+// - s (*scope): names bound while rendering, may be nil
+//
+// Returns:
+// - string: the operand
+// ex: (len .Items)
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func translateOperand(expr string, s *scope) (string, error) {
+	operand, err := translateExpression(expr, s)
+	if err != nil {
+		return "", err
+	}
+
+	tokens, err := lexExpr(operand)
+	if err == nil && len(tokens) == 1 {
+		return operand, nil
+	}
+
+	return "(" + operand + ")", nil
+}
+
+// Parse commands separated by |
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the pipeline
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parsePipeline() (string, error) {
+	pipeline, _, err := p.parseOr()
+	if err != nil {
+		return "", err
+	}
+
+	for p.accept(exprPipe, "|") {
+		t, ok := p.next()
+		if !ok || t.typ != exprIdent || strings.Contains(t.val, ".") {
+			return "", fmt.Errorf("expected a function after |")
+		}
+		command, err := p.parseArguments(t.val)
+		if err != nil {
+			return "", err
+		}
+		pipeline += " | " + command
+	}
+
+	return pipeline, nil
+}
+
+// Parse operands joined with or / ||
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseOr() (string, bool, error) {
+	return p.parseJoined("or", "||", p.parseAnd)
+}
+
+// Parse operands joined with and / &&
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseAnd() (string, bool, error) {
+	return p.parseJoined("and", "&&", p.parseNot)
+}
+
+// Parse operands joined by a boolean operator
+// into a single call of the matching function
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - word (string): the operator as a word
+// - symbol (string): the operator as a symbol
+// - operand (func() (string, bool, error)): parses an operand
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseJoined(word string, symbol string, operand func() (string, bool, error)) (string, bool, error) {
+	first, term, err := operand()
+	if err != nil {
+		return "", false, err
+	}
+
+	operands := []string{wrapOperand(first, term)}
+	for p.accept(exprIdent, word) || p.accept(exprOperator, symbol) {
+		next, term, err := operand()
+		if err != nil {
+			return "", false, err
+		}
+		operands = append(operands, wrapOperand(next, term))
+	}
+
+	if len(operands) == 1 {
+		return first, term, nil
+	}
+
+	return word + " " + strings.Join(operands, " "), false, nil
+}
+
+// Parse an operand negated with not / !
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseNot() (string, bool, error) {
+	if p.accept(exprIdent, "not") {
+		// not takes a single operand
+	} else if !p.accept(exprOperator, "!") {
+		return p.parseComparison()
+	}
+
+	operand, term, err := p.parseNot()
+	if err != nil {
+		return "", false, err
+	}
+
+	return "not " + wrapOperand(operand, term), false, nil
+}
+
+// Parse a comparison such as Count > 3
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseComparison() (string, bool, error) {
+	left, leftTerm, err := p.parseCall()
+	if err != nil {
+		return "", false, err
+	}
+
+	t, ok := p.peek()
+	if !ok || t.typ != exprOperator || comparisonFuncs[t.val] == "" {
+		return left, leftTerm, nil
+	}
+	p.i++
+
+	right, rightTerm, err := p.parseCall()
+	if err != nil {
+		return "", false, err
+	}
+
+	return comparisonFuncs[t.val] + " " + wrapOperand(left, leftTerm) + " " + wrapOperand(right, rightTerm), false, nil
+}
+
+// Parse a function call or a single operand
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed, or an unknown
+// name is followed by arguments without parentheses
+//
+// Since: 0.2.0
+func (p *exprParser) parseCall() (string, bool, error) {
+	if !p.isFunction(p.i) {
+		return p.parseTerm()
+	}
+
+	name := p.tokens[p.i].val
+	if !p.scope.isFunc(name) && !p.startsCall(p.i) {
+		return "", false, fmt.Errorf("unknown function %s, call it as %s(...) or register it in Funcs", name, name)
+	}
+	p.i++
+
+	call, err := p.parseArguments(name)
+	if err != nil {
+		return "", false, err
+	}
+
+	return call, call == name, nil
+}
+
+// Parse the arguments of a function, either
+// space separated or between parentheses
+// ex: printf "%d" Count, printf("%d", Count)
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - name (string): the function
+//
+// Returns:
+// - string: the call
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseArguments(name string) (string, error) {
+	var args []string
+
+	if t, ok := p.peek(); ok && t.typ == exprLeftParen && !t.spaced {
+		p.i++
+		for !p.accept(exprRightParen, ")") {
+			if len(args) > 0 && !p.accept(exprComma, ",") {
+				return "", fmt.Errorf("expected , or ) in the arguments of %s", name)
+			}
+			arg, term, err := p.parseOr()
+			if err != nil {
+				return "", err
+			}
+			args = append(args, wrapOperand(arg, term))
+		}
+	} else {
+		for p.startsTerm(p.i) {
+			arg, term, err := p.parseTerm()
+			if err != nil {
+				return "", err
+			}
+			args = append(args, wrapOperand(arg, term))
+		}
+	}
+
+	return strings.Join(append([]string{name}, args...), " "), nil
+}
+
+// Parse a single operand: a literal, a variable,
+// a name or a parenthesized expression
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - string: the translation
+// - bool: whether the translation is a single operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (p *exprParser) parseTerm() (string, bool, error) {
+	t, ok := p.next()
+	if !ok {
+		return "", false, fmt.Errorf("unexpected end of expression")
+	}
+
+	switch t.typ {
+	case exprField:
+		dot := p.scope.lookupDot()
+		switch {
+		case dot == ".":
+			return t.val, true, nil
+		case t.val == ".":
+			return dot, true, nil
+		}
+		return dot + t.val, true, nil
+	case exprLiteral, exprVariable:
+		return t.val, true, nil
+	case exprIdent:
+		return translatePath(t.val, p.scope), true, nil
+	case exprLeftParen:
+		inner, err := p.parsePipeline()
+		if err != nil {
+			return "", false, err
+		}
+		if !p.accept(exprRightParen, ")") {
+			return "", false, fmt.Errorf("missing )")
+		}
+
+		// Fields of the result, as in (index Users 0).Name
+		term := "(" + inner + ")"
+		if next, ok := p.peek(); ok && next.typ == exprField && !next.spaced {
+			term += next.val
+			p.i++
+		}
+		return term, true, nil
+	}
+
+	return "", false, fmt.Errorf("unexpected %s", t.val)
+}
+
+// Reports whether the token at index i names a
+// function: a known function, or a bare name directly
+// followed by parentheses or by arguments. Only
+// called where an operand starts, so and / or
+// there are the functions rather than operators
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - i (int): token index
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (p *exprParser) isFunction(i int) bool {
+	if i >= len(p.tokens) || p.tokens[i].typ != exprIdent {
+		return false
+	}
+
+	name := p.tokens[i].val
+	if strings.Contains(name, ".") {
+		return false
+	}
+	if _, bound := p.scope.lookup(name); bound {
+		return false
+	}
+
+	return p.scope.isFunc(name) || p.startsCall(i) || p.startsTerm(i+1)
+}
+
+// Reports whether the token at index i is
+// directly followed by an opening parenthesis
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - i (int): token index
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (p *exprParser) startsCall(i int) bool {
+	return i+1 < len(p.tokens) && p.tokens[i+1].typ == exprLeftParen && !p.tokens[i+1].spaced
+}
+
+// Reports whether the token at index i starts an operand
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - i (int): token index
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (p *exprParser) startsTerm(i int) bool {
+	if i >= len(p.tokens) {
+		return false
+	}
+
+	switch t := p.tokens[i]; t.typ {
+	case exprLiteral, exprField, exprVariable, exprLeftParen:
+		return true
+	case exprIdent:
+		return !isOperatorWord(t.val)
+	}
+
+	return false
+}
+
+// Returns the current token without consuming it
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - exprToken
+// - bool: false at the end of the expression
+//
+// Since: 0.2.0
+func (p *exprParser) peek() (exprToken, bool) {
+	if p.i >= len(p.tokens) {
+		return exprToken{}, false
+	}
+	return p.tokens[p.i], true
+}
+
+// Consumes the current token
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Returns:
+// - exprToken
+// - bool: false at the end of the expression
+//
+// Since: 0.2.0
+func (p *exprParser) next() (exprToken, bool) {
+	t, ok := p.peek()
+	if ok {
+		p.i++
+	}
+	return t, ok
+}
+
+// Consumes the current token if it matches
+//
+// Receiver:
+// - p (*exprParser)
+//
+// Params:
+// - typ (exprTokenType): expected kind
+// - val (string): expected source
+//
+// Returns:
+// - bool: whether the token was consumed
+//
+// Since: 0.2.0
+func (p *exprParser) accept(typ exprTokenType, val string) bool {
+	if t, ok := p.peek(); ok && t.typ == typ && t.val == val {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// Translates a dotted name, reading it from the data
+// unless its first part is bound in the scope
+// ex: User.Name -> .User.Name
+//
+// Params:
+// - name (string): the name
+// - s (*scope): names bound while rendering
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func translatePath(name string, s *scope) string {
+	first, rest, _ := strings.Cut(name, ".")
+	if operand, ok := s.lookup(first); ok {
+		if rest == "" {
+			return operand
+		}
+		return operand + "." + rest
+	}
+
+	if dot := s.lookupDot(); dot != "." {
+		return dot + "." + name
+	}
+	return "." + name
+}
+
+// Wraps an operand in parentheses unless
+// it is a single term
+//
+// Params:
+// - operand (string): the translated operand
+// - term (bool): operand is a single term
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func wrapOperand(operand string, term bool) string {
+	if term {
+		return operand
+	}
+	return "(" + operand + ")"
+}
+
+// Reports whether a name is a boolean operator
+//
+// Params:
+// - name (string): the name
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isOperatorWord(name string) bool {
+	return name == "and" || name == "or" || name == "not"
+}
+
+// Finds the end of a name or field chain
+// ex: User.Name
+//
+// Params:
+// - src (string): the source
+// - pos (int): starting offset
+//
+// Returns:
+// - int: offset just past the name
+//
+// Since: 0.2.0
+func scanPath(src string, pos int) int {
+	for pos < len(src) && (isWordChar(src[pos]) || (src[pos] == '.' && pos+1 < len(src) && isWordChar(src[pos+1]))) {
+		pos++
+	}
+	return pos
+}
+
+// Finds the end of a word such as a number
+//
+// Params:
+// - src (string): the source
+// - pos (int): starting offset
+//
+// Returns:
+// - int: offset just past the word
+//
+// Since: 0.2.0
+func scanWord(src string, pos int) int {
+	for pos < len(src) && (isWordChar(src[pos]) || src[pos] == '.') {
+		pos++
+	}
+	return pos
+}
+
+// Reports whether c is a decimal digit
+//
+// Params:
+// - c (byte): the character
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
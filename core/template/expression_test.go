@@ -0,0 +1,114 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestTranslateExpression(t *testing.T) {
+	examples := map[string]string{
+		"title":                        ".title",
+		"User.Name":                    ".User.Name",
+		".Raw":                         ".Raw",
+		"$user.Name":                   "$user.Name",
+		"Count > 3":                    "gt .Count 3",
+		"Count >= -1":                  "ge .Count -1",
+		`Role == "admin"`:              `eq .Role "admin"`,
+		"not Empty":                    "not .Empty",
+		"!User.Admin":                  "not .User.Admin",
+		"A and B or C":                 "or (and .A .B) .C",
+		"A && (B || C)":                "and .A (or .B .C)",
+		"Count > 3 and not User.Admin": "and (gt .Count 3) (not .User.Admin)",
+		"len Items > 0":                "gt (len .Items) 0",
+		"len(Items) == 0":              "eq (len .Items) 0",
+		`printf("%d items", Count)`:    `printf "%d items" .Count`,
+		"Name | upper":                 ".Name | upper",
+		`Name | printf "%q"`:           `.Name | printf "%q"`,
+		"(index Users 0).Name":         "(index .Users 0).Name",
+		"and A B":                      "and .A .B",
+		"eq Role \"admin\"":            "eq .Role \"admin\"",
+		"range $i, $x := .Items":       "range $i, $x := .Items",
+		"$x := 1":                      "$x := 1",
+		"$x = Count":                   "$x = .Count",
+		"$x == 1":                      "eq $x 1",
+		"with User":                    "with .User",
+		"else if A and B":              "else if and .A .B",
+		"else":                         "else",
+		"range $i, $u := Users":        "range $i, $u := .Users",
+		`template "nav" Menu`:          `template "nav" .Menu`,
+		"end":                          "end",
+		"upper(Name)":                  "upper .Name",
+	}
+
+	for example, expected := range examples {
+		result, err := translateExpression(example, nil)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, but got error: %s", example, err.Error())
+		}
+		if result != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, example, result)
+		}
+	}
+}
+
+func TestTranslateExpressionErrors(t *testing.T) {
+	examples := []string{
+		"Count >",
+		"(User.Name",
+		"A and",
+		"Count > 3)",
+		"$x := ",
+		"upper Name",
+		"if A x",
+		"template nav",
+	}
+
+	for _, example := range examples {
+		if _, err := translateExpression(example, nil); err == nil {
+			t.Errorf("Expected an error for %q, but got none", example)
+		}
+	}
+}
+
+func TestTranslateExpressionWithScope(t *testing.T) {
+	s := newScope(nil)
+	s.funcs = map[string]bool{"now": true}
+	s.set("title", `"Hello"`)
+
+	examples := map[string]string{
+		"title":       `"Hello"`,
+		"now":         "now",
+		"title | len": `"Hello" | len`,
+		"Count > 0":   "gt .Count 0",
+	}
+
+	for example, expected := range examples {
+		result, err := translateExpression(example, s)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, but got error: %s", example, err.Error())
+		}
+		if result != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, example, result)
+		}
+	}
+}
+
+func TestTranslateInterpolation(t *testing.T) {
+	examples := map[string]string{
+		"{{ User.Name }}":               "{{ .User.Name }}",
+		"{{- name -}}":                  "{{- .name -}}",
+		"{{.Raw}}":                      "{{.Raw}}",
+		"{{/* note */}}":                "{{/* note */}}",
+		`{{ "x>y" }}`:                   `{{ "x>y" }}`,
+		"{{ Price | printf \"%.2f\" }}": "{{ .Price | printf \"%.2f\" }}",
+	}
+
+	for example, expected := range examples {
+		result, err := translateInterpolation(example[2:len(example)-2], example, nil)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, but got error: %s", example, err.Error())
+		}
+		if result != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, example, result)
+		}
+	}
+}
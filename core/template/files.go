@@ -2,7 +2,7 @@ package template
 
 import (
 	"errors"
-	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 )
@@ -50,6 +50,35 @@ func getContent(filepath string) (string, error) {
 	return string(content), nil
 }
 
+// Read a lamb file from a file system
+//
+// Params:
+// - fsys (fs.FS): file system to read from, nil for the disk
+// - name (string): path of the file
+//
+// Returns:
+// - string: file contents
+// - error: if something goes wrong
+//
+// Since 0.2.0
+func readLambFile(fsys fs.FS, name string) (string, error) {
+	if fsys == nil {
+		return getContent(name)
+	}
+
+	name, err := checkFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
 // Gets the library root path
 //
 // Returns:
@@ -57,10 +86,8 @@ func getContent(filepath string) (string, error) {
 //
 // Since: 0.1.0
 func getLibraryRoot() string {
-	root, err := os.Getwd()
-	if err != nil {
-		fmt.Println("Error getting current working directory:", err)
-	}
+	// Without a working directory paths stay relative
+	root, _ := os.Getwd()
 
 	return root
 }
@@ -3,6 +3,7 @@ package template
 import (
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestCheckFileFail(t *testing.T) {
@@ -45,3 +46,23 @@ func TestGetContent(t *testing.T) {
 		t.Errorf("Expected content to contain %s, but got %s", expected, result)
 	}
 }
+
+func TestReadLambFileFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/home.lamb.html": {Data: []byte("<h1>Home</h1>")},
+	}
+
+	result, err := readLambFile(fsys, "pages/home.lamb.html")
+	if err != nil {
+		t.Errorf("Expected no error, but got error: %s", err.Error())
+		return
+	}
+
+	if result != "<h1>Home</h1>" {
+		t.Errorf("Expected <h1>Home</h1>, but got %s", result)
+	}
+
+	if _, err := readLambFile(fsys, "pages/home.html"); err == nil {
+		t.Errorf("Expected an error, but got none")
+	}
+}
@@ -0,0 +1,393 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Elements whose whitespace is part of their content,
+// it is kept as written when formatting
+//
+// Since: 0.2.0
+var preservedElements = map[string]bool{
+	"pre":      true,
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+}
+
+// Prints a document in the canonical style
+//
+// Fields:
+// - b (strings.Builder): output
+// - preserved ([][2]int): output ranges whose whitespace
+// is kept, such as @verbatim blocks and <pre> content
+//
+// Since: 0.2.0
+type formatter struct {
+	b         strings.Builder
+	preserved [][2]int
+}
+
+// Format lamb source in the canonical style.
+// Interpolations get a single space inside the braces,
+// directives a single space before their arguments and
+// tags a single space between attributes. Trailing
+// whitespace is removed, except inside @verbatim and
+// elements such as <pre>, and the file ends with a newline
+//
+// Params:
+// - src (string): the lamb source
+//
+// Returns:
+// - string: the formatted source
+// - error: if the source cannot be parsed
+//
+// Since: 0.2.0
+func Format(src string) (string, error) {
+	doc, err := Parse("", src)
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{}
+	f.formatNodes(doc.Nodes)
+
+	formatted := strings.TrimRight(f.trimLines(), "\n")
+	if formatted == "" {
+		return "", nil
+	}
+
+	return formatted + "\n", nil
+}
+
+// Removes trailing whitespace from every line
+// ending outside of the preserved ranges
+//
+// Receiver:
+// - f (*formatter)
+//
+// Returns:
+// - string: the output
+//
+// Since: 0.2.0
+func (f *formatter) trimLines() string {
+	out := f.b.String()
+
+	var b strings.Builder
+	line := 0
+	for i := 0; i < len(out); i++ {
+		if out[i] != '\n' {
+			continue
+		}
+
+		text := out[line:i]
+		if !f.isPreserved(i) {
+			text = strings.TrimRight(text, " \t\r")
+		}
+		b.WriteString(text + "\n")
+		line = i + 1
+	}
+
+	text := out[line:]
+	if !f.isPreserved(len(out)) {
+		text = strings.TrimRight(text, " \t\r")
+	}
+	b.WriteString(text)
+
+	return b.String()
+}
+
+// Reports whether an output offset is inside
+// a preserved range
+//
+// Receiver:
+// - f (*formatter)
+//
+// Params:
+// - offset (int): the offset
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (f *formatter) isPreserved(offset int) bool {
+	for _, r := range f.preserved {
+		if offset > r[0] && offset < r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Prints nodes in the canonical style
+//
+// Receiver:
+// - f (*formatter)
+//
+// Params:
+// - nodes ([]Node): nodes to print
+//
+// Since: 0.2.0
+func (f *formatter) formatNodes(nodes []Node) {
+	b := &f.b
+
+	for i, node := range nodes {
+		switch n := node.(type) {
+		case *TextNode:
+			b.WriteString(n.Text)
+		case *ExpressionNode:
+			b.WriteString(formatInterpolation(n.Expr, n.Raw))
+		case *VerbatimNode:
+			start := b.Len()
+			b.WriteString(n.Raw)
+			f.preserved = append(f.preserved, [2]int{start, b.Len()})
+		case *IfNode:
+			for i, branch := range n.Branches {
+				name := "@if"
+				if i > 0 {
+					name = "@elseif"
+				}
+				f.formatDirective(name, branch.Cond, branch.Parens, branch.Body)
+				f.formatNodes(branch.Body)
+			}
+			if n.Else != nil {
+				b.WriteString("@else")
+				f.formatNodes(n.Else.Body)
+			}
+			b.WriteString("@end")
+		case *ForNode:
+			args := n.Value + " in " + n.Collection
+			if n.Key != "" {
+				args = n.Key + ", " + args
+			}
+			f.formatDirective("@for", args, n.Parens, n.Body)
+			f.formatNodes(n.Body)
+			if n.Empty != nil {
+				b.WriteString("@empty")
+				f.formatNodes(n.Empty.Body)
+			}
+			b.WriteString("@end")
+		case *PropsNode:
+			props := make([]string, len(n.Props))
+			for i, prop := range n.Props {
+				props[i] = prop.String()
+			}
+			b.WriteString("@props(" + strings.Join(props, ", ") + ")")
+		case *ExtendsNode:
+			b.WriteString("@extends(" + strconv.Quote(n.Layout) + ")")
+		case *SectionNode:
+			b.WriteString("@section(" + strconv.Quote(n.Name) + ")")
+			f.formatNodes(n.Body)
+			b.WriteString("@end")
+		case *YieldNode:
+			b.WriteString("@yield(" + strconv.Quote(n.Name))
+			if n.Default != "" {
+				b.WriteString(", " + strconv.Quote(n.Default))
+			}
+			b.WriteString(")")
+		case *IncludeNode:
+			b.WriteString("@include(" + strconv.Quote(n.Name))
+			if n.Data != "" {
+				b.WriteString(", " + n.Data)
+			}
+			b.WriteString(")")
+		case *WithNode:
+			f.formatDirective("@with", n.Value, n.Parens, n.Body)
+			f.formatNodes(n.Body)
+			if n.Else != nil {
+				b.WriteString("@else")
+				f.formatNodes(n.Else.Body)
+			}
+			b.WriteString("@end")
+		case *LetNode:
+			f.formatDirective("@let", n.Name+" = "+n.Value, n.Parens, nodes[i+1:])
+		case *SwitchNode:
+			f.formatDirective("@switch", n.Value, n.Parens, []Node{&TextNode{Text: n.Lead}})
+			b.WriteString(n.Lead)
+			for _, branch := range n.Cases {
+				f.formatDirective("@case", strings.Join(splitArguments(branch.Cond), ", "), branch.Parens, branch.Body)
+				f.formatNodes(branch.Body)
+			}
+			if n.Default != nil {
+				b.WriteString("@default")
+				f.formatNodes(n.Default.Body)
+			}
+			b.WriteString("@end")
+		case *ComponentNode:
+			f.formatElement(&n.ElementNode)
+		case *ElementNode:
+			f.formatElement(n)
+		}
+	}
+}
+
+// Prints a directive and its arguments. They are put in
+// parentheses when the source did so or when the content
+// after them is on the same line, since arguments without
+// parentheses run to the end of the line
+//
+// Receiver:
+// - f (*formatter)
+//
+// Params:
+// - name (string): the directive
+// ex: @if
+// - args (string): the arguments
+// - parens (bool): the source used parentheses
+// - next ([]Node): nodes following the directive
+//
+// Since: 0.2.0
+func (f *formatter) formatDirective(name string, args string, parens bool, next []Node) {
+	if parens || !startsNewLine(next) {
+		f.b.WriteString(name + "(" + args + ")")
+		return
+	}
+
+	f.b.WriteString(name + " " + args)
+}
+
+// Reports whether nodes start with a line break,
+// ignoring spaces before it
+//
+// Params:
+// - nodes ([]Node): the nodes
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func startsNewLine(nodes []Node) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+
+	text, ok := nodes[0].(*TextNode)
+	if !ok {
+		return false
+	}
+
+	rest := strings.TrimLeft(text.Text, " \t")
+	return strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n")
+}
+
+// Prints an element in the canonical style
+//
+// Receiver:
+// - f (*formatter)
+//
+// Params:
+// - n (*ElementNode): the element
+//
+// Since: 0.2.0
+func (f *formatter) formatElement(n *ElementNode) {
+	b := &f.b
+	b.WriteString("<" + n.Name)
+
+	for _, attr := range n.Attrs {
+		b.WriteString(formatSpace(attr.Space, " "))
+
+		switch attr.Kind {
+		case AttributeExpression:
+			b.WriteString(formatInterpolation(attr.Value, "{{"+attr.Value+"}}"))
+		case AttributeDirective:
+			b.WriteString("@attributes(" + strings.TrimSpace(attr.Value) + ")")
+		case AttributeBinding:
+			b.WriteString(":" + attr.Name + "=" + formatAttributeValue(attr.Quote, strings.TrimSpace(attr.Value)))
+		default:
+			b.WriteString(attr.Name)
+			if attr.HasValue {
+				b.WriteString("=" + formatAttributeValue(attr.Quote, attr.Value))
+			}
+		}
+	}
+
+	if n.SelfClosing {
+		b.WriteString(formatSpace(n.Space, " ") + "/>")
+	} else {
+		b.WriteString(formatSpace(n.Space, "") + ">")
+	}
+
+	start := b.Len()
+	f.formatNodes(n.Children)
+	if preservedElements[strings.ToLower(n.Name)] {
+		f.preserved = append(f.preserved, [2]int{start, b.Len()})
+	}
+
+	if n.Closed {
+		b.WriteString("</" + n.Name + ">")
+	}
+}
+
+// Quotes an attribute value, unquoted values get
+// double quotes unless they contain one
+//
+// Params:
+// - quote (string): the original quote
+// - value (string): the value
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func formatAttributeValue(quote string, value string) string {
+	if quote == "" {
+		quote = `"`
+		if strings.Contains(value, `"`) {
+			quote = "'"
+		}
+	}
+
+	return quote + value + quote
+}
+
+// Prints an interpolation with a single space
+// inside the braces, keeping trim markers
+//
+// Params:
+// - expr (string): text between the braces
+// - raw (string): full source of the interpolation
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func formatInterpolation(expr string, raw string) string {
+	body := strings.TrimSpace(expr)
+	if strings.HasPrefix(body, "/*") || body == "" {
+		return raw
+	}
+
+	open, close := "{{ ", " }}"
+	if strings.HasPrefix(body, "- ") {
+		open = "{{- "
+		body = strings.TrimSpace(body[2:])
+	}
+	if strings.HasSuffix(body, " -") {
+		close = " -}}"
+		body = strings.TrimSpace(body[:len(body)-2])
+	}
+
+	return open + body + close
+}
+
+// Normalizes whitespace inside a tag. Whitespace
+// containing a line break is kept so attributes
+// spread over several lines stay that way
+//
+// Params:
+// - space (string): the original whitespace
+// - canonical (string): replacement for whitespace on a single line
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func formatSpace(space string, canonical string) string {
+	if strings.Contains(space, "\n") {
+		return space
+	}
+
+	return canonical
+}
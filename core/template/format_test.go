@@ -0,0 +1,156 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	example := "<h1>{{title}}</h1>   \n@if   LoggedIn\n<ui-link   href=/home :title=User.Name class='a \"b\"'/>\n@else\n<p>{{- name -}}</p>\n@end\n@for  i,user in users\n<ui-card\n    title=\"{{ user }}\">{{ user }}</ui-card  >\n@empty\nnone\n@end\n\n\n"
+
+	expected := "<h1>{{ title }}</h1>\n@if LoggedIn\n<ui-link href=\"/home\" :title=\"User.Name\" class='a \"b\"' />\n@else\n<p>{{- name -}}</p>\n@end\n@for i, user in users\n<ui-card\n    title=\"{{ user }}\">{{ user }}</ui-card>\n@empty\nnone\n@end\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatProps(t *testing.T) {
+	example := "@props( title:string,count : int=0, variant: \"a\"|\"b\" )\n<h1>{{ title }}</h1>\n"
+
+	expected := "@props(title: string, count: int = 0, variant: \"a\"|\"b\")\n<h1>{{ title }}</h1>\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatSwitch(t *testing.T) {
+	example := "@switch   Status\n@case \"a\" ,\"b\"\nA\n@default\nB\n@end\n"
+
+	expected := "@switch Status\n@case \"a\", \"b\"\nA\n@default\nB\n@end\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatKeepsVerbatim(t *testing.T) {
+	example := "@@{{x}}\n@verbatim {{x}}  <ui-a   b=c> @endverbatim\n"
+
+	expected := "@@{{ x }}\n@verbatim {{x}}  <ui-a   b=c> @endverbatim\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatLayouts(t *testing.T) {
+	example := "@extends( \"layouts/app\" )\n@section(\"title\")\n<title>@yield( \"name\",\"Lamb\" )</title>\n@end\n"
+
+	expected := "@extends(\"layouts/app\")\n@section(\"title\")\n<title>@yield(\"name\", \"Lamb\")</title>\n@end\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatInclude(t *testing.T) {
+	example := "<nav>@include( \"partials/nav\" ,  Menu )</nav>\n@include('partials/footer')\n"
+
+	expected := "<nav>@include(\"partials/nav\", Menu)</nav>\n@include(\"partials/footer\")\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestFormatIsStable(t *testing.T) {
+	example := "<div class=\"a\"  @attributes( \"id\": \"b\" )>\n  {{ .Raw }}\n</div>\n"
+
+	first, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	second, _ := Format(first)
+	if first != second {
+		t.Errorf("Expected %q, but got %q", first, second)
+	}
+}
+
+func TestFormatKeepsParentheses(t *testing.T) {
+	examples := map[string]string{
+		"<p>@if(LoggedIn)<b>hi</b>@end</p>\n":                      "<p>@if(LoggedIn)<b>hi</b>@end</p>\n",
+		"<ul>@for(user in users)<li>{{user.Name}}</li>@end</ul>\n": "<ul>@for(user in users)<li>{{ user.Name }}</li>@end</ul>\n",
+		"@switch(Kind)@case(\"a\")A@default B @end\n":              "@switch(Kind)@case(\"a\")A@default B @end\n",
+		"@if  Ready\n<p>ok</p>\n@elseif(Busy) wait\n@end\n":        "@if Ready\n<p>ok</p>\n@elseif(Busy) wait\n@end\n",
+		"@let(total = len .Items)<p>{{ $total }}</p>\n":            "@let(total = len .Items)<p>{{ $total }}</p>\n",
+		"@with(User)<p>{{ .Name }}</p>@else<p>none</p>@end\n":      "@with(User)<p>{{ .Name }}</p>@else<p>none</p>@end\n",
+		"@switch Kind\n@case \"a\"\nA\n@end\n":                     "@switch Kind\n@case \"a\"\nA\n@end\n",
+	}
+
+	for example, expected := range examples {
+		result, err := Format(example)
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if result != expected {
+			t.Errorf("Expected %q, but got %q", expected, result)
+		}
+
+		again, err := Format(result)
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if again != result {
+			t.Errorf("Expected %q, but got %q", result, again)
+		}
+	}
+}
+
+func TestFormatKeepsPreservedWhitespace(t *testing.T) {
+	example := "<div>  \n<pre>a  \nb\t\n</pre>  \n<textarea>x  \n</textarea>\n@verbatim\n{{ raw }}  \n@endverbatim\n</div>\n"
+
+	expected := "<div>\n<pre>a  \nb\t\n</pre>\n<textarea>x  \n</textarea>\n@verbatim\n{{ raw }}  \n@endverbatim\n</div>\n"
+
+	result, err := Format(example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
@@ -0,0 +1,1653 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Turns parsed lamb documents into go template source,
+// expanding components along the way
+//
+// Fields:
+// - options (Options): where pages and components are read from,
+// components are left as they are without a component directory
+// - docs (map[docKey]*Document): parsed files
+// - dependencies ([]string): paths of every component, layout
+// and partial the generator tried to load, including missing ones
+// - root (*scope): scope of pages, knows the registered functions
+// - variables (map[string]int): template variables declared so
+// far by name, used to keep them from shadowing each other
+// - partials (map[string]bool): partials defined so far by name
+// - defines (strings.Builder): define blocks of the partials,
+// written after the page
+// - roots ([]ComponentRoot): places components are searched, in order
+// - prefixes ([]string): tag prefixes of components
+//
+// Since: 0.2.0
+type generator struct {
+	options      Options
+	docs         map[docKey]*Document
+	dependencies []string
+	root         *scope
+	variables    map[string]int
+	partials     map[string]bool
+	defines      strings.Builder
+	roots        []ComponentRoot
+	prefixes     []string
+}
+
+// HTML attributes that are on whenever present,
+// whatever their value
+//
+// Since: 0.2.0
+var booleanAttributes = map[string]bool{
+	"allowfullscreen": true,
+	"async":           true,
+	"autofocus":       true,
+	"autoplay":        true,
+	"checked":         true,
+	"controls":        true,
+	"default":         true,
+	"defer":           true,
+	"disabled":        true,
+	"formnovalidate":  true,
+	"hidden":          true,
+	"inert":           true,
+	"ismap":           true,
+	"loop":            true,
+	"multiple":        true,
+	"muted":           true,
+	"nomodule":        true,
+	"novalidate":      true,
+	"open":            true,
+	"playsinline":     true,
+	"readonly":        true,
+	"required":        true,
+	"reversed":        true,
+	"selected":        true,
+}
+
+// Identifies a parsed file in the generator cache
+//
+// Fields:
+// - component (bool): file was read from a component root
+// - root (int): index of the component root
+// - path (string): path of the file
+//
+// Since: 0.2.0
+type docKey struct {
+	component bool
+	root      int
+	path      string
+}
+
+// State of the document currently being rendered
+//
+// Fields:
+// - doc (*Document): the document
+// - attrs (Attributes): attributes passed by the caller
+// - slots (map[string][]Node): content passed by the caller by slot
+// name, the default slot has an empty name
+// - parent (*frame): frame of the caller, used to render slots
+// - chain ([]string): files from the page down to this document
+// - components ([]docKey): component files from the page down to
+// this document, used to find components including themselves
+// - scope (*scope): names bound in the document, such as props
+// - sections (map[string]*section): sections filled by the pages
+// extending this layout, by name
+//
+// Since: 0.2.0
+type frame struct {
+	doc        *Document
+	attrs      Attributes
+	slots      map[string][]Node
+	parent     *frame
+	chain      []string
+	components []docKey
+	scope      *scope
+	sections   map[string]*section
+}
+
+// Content of an @section, with the frame
+// it is rendered in
+//
+// Fields:
+// - nodes ([]Node): content of the section
+// - frame (*frame): frame of the file declaring it
+//
+// Since: 0.2.0
+type section struct {
+	nodes []Node
+	frame *frame
+}
+
+// Translates an expression of the document to
+// a go template pipeline
+//
+// Receiver:
+// - f (*frame)
+//
+// Params:
+// - expr (string): the expression
+// - pos (Pos): position of the directive or attribute
+//
+// Returns:
+// - string: the pipeline
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (f *frame) expression(expr string, pos Pos) (string, error) {
+	pipeline, err := translateExpression(expr, f.scope)
+	if err != nil {
+		return "", f.expressionError(expr, pos, err)
+	}
+
+	return pipeline, nil
+}
+
+// Translates an expression of the document to
+// a single go template operand
+//
+// Receiver:
+// - f (*frame)
+//
+// Params:
+// - expr (string): the expression
+// - pos (Pos): position of the directive or attribute
+//
+// Returns:
+// - string: the operand
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (f *frame) operand(expr string, pos Pos) (string, error) {
+	operand, err := translateOperand(expr, f.scope)
+	if err != nil {
+		return "", f.expressionError(expr, pos, err)
+	}
+
+	return operand, nil
+}
+
+// Explains a malformed expression in the arguments of
+// a directive written without parentheses, which end
+// at the line, a tag or the next directive
+//
+// Params:
+// - err (error): the error
+// - parens (bool): the arguments were written in parentheses
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func bareArguments(err error, parens bool) error {
+	var lambErr *Error
+	if !parens && errors.As(err, &lambErr) {
+		lambErr.Message += ", put the arguments in parentheses when content follows them on the line"
+	}
+
+	return err
+}
+
+// Reports a malformed expression of the document
+//
+// Receiver:
+// - f (*frame)
+//
+// Params:
+// - expr (string): the expression
+// - pos (Pos): position of the directive or attribute
+// - err (error): why it cannot be translated
+//
+// Returns:
+// - error: the error with its position
+//
+// Since: 0.2.0
+func (f *frame) expressionError(expr string, pos Pos, err error) error {
+	return f.doc.errorf(pos, "malformed expression %q: %s", strings.TrimSpace(expr), err.Error())
+}
+
+// Creates a generator
+//
+// Params:
+// - options (Options): where pages and components are read from
+//
+// Returns:
+// - *generator
+//
+// Since: 0.2.0
+func newGenerator(options Options) *generator {
+	root := newScope(nil)
+	root.funcs = make(map[string]bool)
+	for name := range options.Funcs {
+		root.funcs[name] = true
+	}
+
+	var roots []ComponentRoot
+	if options.ComponentDir != "" || options.ComponentFS != nil {
+		prefixes := options.ComponentPrefixes
+		if len(prefixes) == 0 {
+			prefixes = []string{componentPrefix}
+		}
+		for _, prefix := range prefixes {
+			roots = append(roots, ComponentRoot{Prefix: prefix, Dir: options.ComponentDir, FS: options.ComponentFS})
+		}
+	}
+	roots = append(roots, options.ComponentRoots...)
+
+	// Without any root, ui- components are left as they are
+	prefixes := []string{componentPrefix}
+	if len(roots) > 0 {
+		prefixes = nil
+	}
+	for i := range roots {
+		if roots[i].Prefix == "" {
+			roots[i].Prefix = componentPrefix
+		}
+		if !slices.Contains(prefixes, roots[i].Prefix) {
+			prefixes = append(prefixes, roots[i].Prefix)
+		}
+	}
+
+	return &generator{
+		options:   options,
+		docs:      make(map[docKey]*Document),
+		root:      root,
+		variables: make(map[string]int),
+		partials:  make(map[string]bool),
+		roots:     roots,
+		prefixes:  prefixes,
+	}
+}
+
+// Picks the name of a new template variable. Names
+// already declared get a numeric suffix so that
+// components cannot overwrite variables of the caller
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - name (string): preferred name
+//
+// Returns:
+// - string: the variable
+// ex: $total
+//
+// Since: 0.2.0
+func (g *generator) variable(name string) string {
+	g.variables[name]++
+	if n := g.variables[name]; n > 1 {
+		return fmt.Sprintf("$%s_%d", name, n)
+	}
+
+	return "$" + name
+}
+
+// Reads and parses a page
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - path (string): path to the page, relative to
+// the page file system when one is set
+//
+// Returns:
+// - *Document: the parsed page
+// - error: if the page cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) loadPage(path string) (*Document, error) {
+	return g.load(docKey{path: path}, g.options.PageFS)
+}
+
+// Reads and parses a component
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - root (int): index of the component root
+// - path (string): path to the component, relative to
+// the file system of the root when it has one
+//
+// Returns:
+// - *Document: the parsed component
+// - error: if the component cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) loadComponent(root int, path string) (*Document, error) {
+	return g.load(docKey{component: true, root: root, path: path}, g.roots[root].FS)
+}
+
+// Finds and parses a component by tag, trying each
+// of its candidate files in every root with a matching
+// prefix. The first file found wins
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - tag (string): tag name of the component
+// ex: ui-forms.input
+//
+// Returns:
+// - docKey: root and path of the component, the
+// first candidate when none exists
+// - *Document: the parsed component
+// - error: if the component cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) findComponent(tag string) (docKey, *Document, error) {
+	var first docKey
+	var missing error
+
+	for i, root := range g.roots {
+		if !strings.HasPrefix(tag, root.Prefix) || len(tag) == len(root.Prefix) {
+			continue
+		}
+
+		for _, candidate := range componentFilePaths(root.Dir, tag[len(root.Prefix):]) {
+			key := docKey{component: true, root: i, path: candidate}
+			doc, err := g.loadComponent(i, candidate)
+			if !errors.Is(err, fs.ErrNotExist) {
+				return key, doc, err
+			}
+			if missing == nil {
+				first, missing = key, err
+			}
+		}
+	}
+
+	return first, nil, missing
+}
+
+// Names a component file in the chain of an error.
+// Roots read from a file system may hold the same
+// paths, so their files are qualified by the prefix
+// ex: button.lamb.html (acme-)
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - key (docKey): the component file
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (g *generator) componentFileName(key docKey) string {
+	root := g.roots[key.root]
+	if root.FS == nil {
+		return key.path
+	}
+
+	return fmt.Sprintf("%s (%s)", key.path, root.Prefix)
+}
+
+// Lists the components of every root by tag name,
+// skipping files that cannot be read
+//
+// Receiver:
+// - g (*generator)
+//
+// Returns:
+// - []string: tag names
+// ex: ui-button, ui-forms.input
+//
+// Since: 0.2.0
+func (g *generator) listComponents() []string {
+	var tags []string
+
+	for _, root := range g.roots {
+		fsys, dir := root.FS, path.Clean(root.Dir)
+		if fsys == nil {
+			fsys, dir = os.DirFS(filepath.Clean(root.Dir)), "."
+		}
+
+		fs.WalkDir(fsys, dir, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(file, ".lamb.html") {
+				return nil
+			}
+
+			name := strings.TrimSuffix(strings.TrimPrefix(file, dir+"/"), ".lamb.html")
+			if name == "index" {
+				return nil
+			}
+			name = strings.TrimSuffix(name, "/index")
+
+			tag := root.Prefix + strings.ReplaceAll(name, "/", ".")
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+			return nil
+		})
+	}
+
+	return tags
+}
+
+// Suggests existing components for the tag
+// of an unknown one, closest first
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - tag (string): tag of the unknown component
+//
+// Returns:
+// - []string: up to three tag names
+//
+// Since: 0.2.0
+func (g *generator) suggestComponents(tag string) []string {
+	return closestNames(tag, g.listComponents(), 3)
+}
+
+// Reads and parses a layout from the page file system
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - name (string): layout name relative to the layout directory
+// ex: layouts/app
+//
+// Returns:
+// - string: path to the layout
+// - *Document: the parsed layout
+// - error: if the layout cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) loadLayout(name string) (string, *Document, error) {
+	dir := g.options.LayoutDir
+	if dir == "" {
+		dir = g.options.PageDir
+	}
+
+	return g.loadNamed(dir, name)
+}
+
+// Reads and parses a partial from the page file system
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - name (string): partial name relative to the partial directory
+// ex: partials/nav
+//
+// Returns:
+// - string: path to the partial
+// - *Document: the parsed partial
+// - error: if the partial cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) loadPartial(name string) (string, *Document, error) {
+	dir := g.options.PartialDir
+	if dir == "" {
+		dir = g.options.PageDir
+	}
+
+	return g.loadNamed(dir, name)
+}
+
+// Reads and parses a file of the page file system
+// referenced by name, such as a layout or a partial
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - dir (string): directory the name is relative to
+// - name (string): the name, the .lamb.html extension is optional
+//
+// Returns:
+// - string: path to the file
+// - *Document: the parsed file
+// - error: if the file cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) loadNamed(dir string, name string) (string, *Document, error) {
+	if !strings.HasSuffix(name, ".lamb.html") {
+		name += ".lamb.html"
+	}
+
+	filePath := filepath.Join(dir, name)
+	if g.options.PageFS != nil {
+		filePath = path.Join(dir, name)
+	}
+
+	if _, ok := g.docs[docKey{path: filePath}]; !ok {
+		g.dependencies = append(g.dependencies, filePath)
+	}
+
+	doc, err := g.loadPage(filePath)
+	return filePath, doc, err
+}
+
+// Reads and parses a lamb file, caching the result
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - key (docKey): cache key of the file
+// - fsys (fs.FS): file system to read from, nil for the disk
+//
+// Returns:
+// - *Document: the parsed file
+// - error: if the file cannot be read or parsed
+//
+// Since: 0.2.0
+func (g *generator) load(key docKey, fsys fs.FS) (*Document, error) {
+	if doc, ok := g.docs[key]; ok {
+		return doc, nil
+	}
+
+	if key.component {
+		g.dependencies = append(g.dependencies, key.path)
+	}
+
+	content, err := readLambFile(fsys, key.path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parse(key.path, content, g.prefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	g.docs[key] = doc
+	return doc, nil
+}
+
+// Renders a document to go template source
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - doc (*Document): the document
+//
+// Returns:
+// - string: the go template source
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) generate(doc *Document) (string, error) {
+	var b strings.Builder
+
+	chain := []string{doc.Name}
+	err := g.renderDocument(&b, &frame{doc: doc, chain: chain, scope: g.root})
+	if err != nil {
+		return "", withChain(err, chain)
+	}
+
+	b.WriteString(g.defines.String())
+	return b.String(), nil
+}
+
+// Renders a page or layout. A file extending a layout
+// renders the layout with its sections filled in
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - f (*frame): frame of the file, its sections are
+// the ones filled by files extending it
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderDocument(b *strings.Builder, f *frame) error {
+	extends := f.doc.Extends
+	if extends == nil {
+		return g.render(b, f.doc.Nodes, f)
+	}
+
+	// Sections of the extending file win over the ones declared here
+	sections := make(map[string]*section)
+	for _, node := range f.doc.Nodes {
+		if s, ok := node.(*SectionNode); ok {
+			sections[s.Name] = &section{nodes: s.Body, frame: f}
+		}
+	}
+	for name, s := range f.sections {
+		sections[name] = s
+	}
+
+	layoutPath, layout, err := g.loadLayout(extends.Layout)
+	if errors.Is(err, fs.ErrNotExist) {
+		unknown := f.doc.errorf(extends.Pos, "unknown layout %s, %s does not exist", extends.Layout, layoutPath)
+		unknown.(*Error).Err = err
+		return withChain(unknown, f.chain)
+	}
+	chain := append(append([]string{}, f.chain...), layoutPath)
+	if err != nil {
+		return withChain(err, chain)
+	}
+
+	for _, included := range f.chain {
+		if included == layoutPath {
+			return withChain(f.doc.errorf(extends.Pos, "layout %s extends itself", extends.Layout), f.chain)
+		}
+	}
+
+	return g.renderDocument(b, &frame{doc: layout, chain: chain, scope: g.root, sections: sections})
+}
+
+// Renders nodes into the builder
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - nodes ([]Node): nodes to render
+// - f (*frame): frame the nodes belong to
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) render(b *strings.Builder, nodes []Node, f *frame) error {
+	for i, node := range nodes {
+		var err error
+
+		switch n := node.(type) {
+		case *TextNode:
+			text := n.Text
+			if i > 0 {
+				// The line of an @props header is dropped entirely
+				if _, ok := nodes[i-1].(*PropsNode); ok {
+					text = strings.TrimPrefix(strings.TrimPrefix(text, "\r"), "\n")
+				}
+			}
+			if n.RawText && text == "<!--"+processAttribute {
+				text = "<!--"
+			} else if n.RawText {
+				text = escapeBraces(text)
+			}
+			b.WriteString(text)
+		case *ExpressionNode:
+			var action string
+			action, err = translateInterpolation(n.Expr, n.Raw, f.scope)
+			if err != nil {
+				err = f.expressionError(n.Expr, n.Pos, err)
+				break
+			}
+			b.WriteString(action)
+		case *VerbatimNode:
+			b.WriteString(escapeBraces(n.Text))
+		case *IfNode:
+			err = g.renderIf(b, n, f)
+		case *ForNode:
+			err = g.renderFor(b, n, f)
+		case *SwitchNode:
+			err = g.renderSwitch(b, n, f)
+		case *WithNode:
+			err = g.renderWith(b, n, f)
+		case *YieldNode:
+			if s := f.sections[n.Name]; s != nil {
+				err = g.render(b, s.nodes, s.frame)
+			} else {
+				b.WriteString(n.Default)
+			}
+		case *IncludeNode:
+			err = g.renderInclude(b, n.Pos, n.Name, n.Data, f)
+		case *LetNode:
+			var value string
+			value, err = f.expression(n.Value, n.Pos)
+			if err != nil {
+				err = bareArguments(err, n.Parens)
+				break
+			}
+			name := g.variable(n.Name)
+			fmt.Fprintf(b, "{{ %s := %s }}", name, value)
+
+			// The variable is visible to the following nodes
+			next := *f
+			next.scope = newScope(f.scope)
+			next.scope.set(n.Name, name)
+			f = &next
+		case *ComponentNode:
+			err = g.renderComponent(b, n, f)
+		case *ElementNode:
+			err = g.renderElement(b, n, f)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Renders an @if block
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*IfNode): the block
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderIf(b *strings.Builder, n *IfNode, f *frame) error {
+	for i, branch := range n.Branches {
+		cond, err := f.expression(branch.Cond, branch.Pos)
+		if err != nil {
+			return bareArguments(err, branch.Parens)
+		}
+
+		if i == 0 {
+			fmt.Fprintf(b, "{{ if %s }}", cond)
+		} else {
+			fmt.Fprintf(b, "{{ else if %s }}", cond)
+		}
+
+		if err := g.render(b, branch.Body, f); err != nil {
+			return err
+		}
+	}
+
+	if n.Else != nil {
+		b.WriteString("{{ else }}")
+		if err := g.render(b, n.Else.Body, f); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("{{ end }}")
+	return nil
+}
+
+// Renders an @for block. The loop variables become
+// go template variables and names referring to them
+// in the body are rewritten
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*ForNode): the block
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderFor(b *strings.Builder, n *ForNode, f *frame) error {
+	collection, err := f.expression(n.Collection, n.Pos)
+	if err != nil {
+		return bareArguments(err, n.Parens)
+	}
+
+	// Names in the body are read from the item
+	body := *f
+	body.scope = newScope(f.scope)
+	body.scope.dot = "."
+
+	variables := g.variable(n.Value)
+	body.scope.set(n.Value, variables)
+	if n.Key != "" {
+		key := g.variable(n.Key)
+		variables = key + ", " + variables
+		body.scope.set(n.Key, key)
+	}
+
+	fmt.Fprintf(b, "{{ range %s := %s }}", variables, collection)
+
+	if err := g.render(b, n.Body, &body); err != nil {
+		return err
+	}
+
+	if n.Empty != nil {
+		b.WriteString("{{ else }}")
+		if err := g.render(b, n.Empty.Body, f); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("{{ end }}")
+	return nil
+}
+
+// Renders an @switch block as an if chain comparing
+// the value with the values of each @case
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*SwitchNode): the block
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderSwitch(b *strings.Builder, n *SwitchNode, f *frame) error {
+	value, err := f.operand(n.Value, n.Pos)
+	if err != nil {
+		return bareArguments(err, n.Parens)
+	}
+
+	for i, branch := range n.Cases {
+		operands := []string{value}
+		for _, option := range splitArguments(branch.Cond) {
+			operand, err := f.operand(option, branch.Pos)
+			if err != nil {
+				return bareArguments(err, branch.Parens)
+			}
+			operands = append(operands, operand)
+		}
+
+		keyword := "if"
+		if i > 0 {
+			keyword = "else if"
+		}
+		fmt.Fprintf(b, "{{ %s eq %s }}", keyword, strings.Join(operands, " "))
+
+		if err := g.render(b, branch.Body, f); err != nil {
+			return err
+		}
+	}
+
+	if n.Default != nil {
+		b.WriteString("{{ else }}")
+		if err := g.render(b, n.Default.Body, f); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("{{ end }}")
+	return nil
+}
+
+// Renders an @with block. Names inside of it are
+// read from the value unless bound in the scope
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*WithNode): the block
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderWith(b *strings.Builder, n *WithNode, f *frame) error {
+	value, err := f.expression(n.Value, n.Pos)
+	if err != nil {
+		return bareArguments(err, n.Parens)
+	}
+	fmt.Fprintf(b, "{{ with %s }}", value)
+
+	body := *f
+	body.scope = newScope(f.scope)
+	body.scope.dot = "."
+
+	if err := g.render(b, n.Body, &body); err != nil {
+		return err
+	}
+
+	if n.Else != nil {
+		b.WriteString("{{ else }}")
+		if err := g.render(b, n.Else.Body, f); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("{{ end }}")
+	return nil
+}
+
+// Renders a html element. A <slot /> inside a component
+// is replaced with the caller's content for the slot named
+// by its name attribute, or with its own content when the
+// caller provides nothing
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*ElementNode): the element
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func (g *generator) renderElement(b *strings.Builder, n *ElementNode, f *frame) error {
+	if n.Name == "slot" && f.parent != nil {
+		content := f.slots[attributeValue(n.Attrs, "name")]
+		if isBlank(content) {
+			return g.render(b, n.Children, f)
+		}
+		return g.render(b, content, f.parent)
+	}
+
+	if err := g.renderStartTag(b, n, f); err != nil {
+		return err
+	}
+
+	if err := g.render(b, n.Children, f); err != nil {
+		return err
+	}
+
+	if n.Closed {
+		fmt.Fprintf(b, "</%s>", n.Name)
+	}
+
+	return nil
+}
+
+// Renders an @include as a call of the template defined
+// for the partial. The partial is defined the first time
+// it is included, so it may include itself
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - pos (Pos): position of the @include
+// - name (string): partial name
+// - data (string): expression the partial reads from
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if the partial cannot be loaded
+//
+// Since: 0.2.0
+func (g *generator) renderInclude(b *strings.Builder, pos Pos, name string, data string, f *frame) error {
+	name = strings.TrimSuffix(path.Clean(name), ".lamb.html")
+
+	operand := f.scope.lookupDot()
+	if data != "" {
+		var err error
+		operand, err = f.expression(data, pos)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(b, "{{ template %s %s }}", strconv.Quote(name), operand)
+
+	if g.partials[name] {
+		return nil
+	}
+	g.partials[name] = true
+
+	partialPath, doc, err := g.loadPartial(name)
+	if err == nil && doc.Extends != nil {
+		err = doc.errorf(doc.Extends.Pos, "partials cannot use @extends")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		unknown := f.doc.errorf(pos, "unknown partial %s, %s does not exist", name, partialPath)
+		unknown.(*Error).Err = err
+		return withChain(unknown, f.chain)
+	}
+	chain := append(append([]string{}, f.chain...), partialPath)
+	if err != nil {
+		return withChain(err, chain)
+	}
+
+	var define strings.Builder
+	fmt.Fprintf(&define, "{{ define %s }}", strconv.Quote(name))
+	if err := g.render(&define, doc.Nodes, &frame{doc: doc, chain: chain, scope: g.root}); err != nil {
+		return err
+	}
+	define.WriteString("{{ end }}")
+
+	g.defines.WriteString(define.String())
+	return nil
+}
+
+// Renders the start tag of an element, applying
+// the @attributes directive and attribute bindings
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*ElementNode): the element
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if an included partial cannot be loaded
+//
+// Since: 0.2.0
+func (g *generator) renderStartTag(b *strings.Builder, n *ElementNode, f *frame) error {
+	b.WriteString("<" + n.Name)
+
+	for _, attr := range n.Attrs {
+		if attr.Kind == AttributeNormal && attr.Name == processAttribute && rawTextElements[strings.ToLower(n.Name)] {
+			continue
+		}
+		b.WriteString(attr.Space)
+
+		switch attr.Kind {
+		case AttributeExpression:
+			action, err := translateInterpolation(attr.Value, "{{"+attr.Value+"}}", f.scope)
+			if err != nil {
+				return f.expressionError(attr.Value, attr.Pos, err)
+			}
+			b.WriteString(action)
+		case AttributeDirective:
+			childAttrs := parseAttributesString(attr.Value)
+			for key, value := range childAttrs {
+				rendered, err := f.renderInline(value, attr.Pos)
+				if err != nil {
+					return err
+				}
+				childAttrs[key] = rendered
+			}
+			// Attributes of the caller override the ones of the component
+			b.WriteString(f.attrs.clone().withDefaults(childAttrs).toString())
+		case AttributeBinding:
+			if booleanAttributes[strings.ToLower(attr.Name)] {
+				// Boolean attributes are on whenever present, so the
+				// attribute is only written when the value is true
+				cond, err := f.expression(attr.Value, attr.Pos)
+				if err != nil {
+					return err
+				}
+				b.WriteString("{{ if " + cond + " }}" + attr.Name + "{{ end }}")
+				continue
+			}
+			action, err := f.bindAttribute(attr.Value, attr.Pos)
+			if err != nil {
+				return err
+			}
+			b.WriteString(attr.Name + `="` + action + `"`)
+		default:
+			b.WriteString(attr.Name)
+			if attr.HasValue {
+				value, err := g.renderValue(attr.Value, attr.Pos, f)
+				if err != nil {
+					return err
+				}
+				b.WriteString(attr.Eq + attr.Quote + value + attr.Quote)
+			}
+		}
+	}
+
+	b.WriteString(n.Space)
+	if n.SelfClosing {
+		b.WriteString("/>")
+	} else {
+		b.WriteString(">")
+	}
+
+	return nil
+}
+
+// Converts an attribute value, rendering the
+// interpolations and @include directives in it
+// ex: nav @include('partials/active', Page)
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - text (string): the value
+// - pos (Pos): position of the attribute
+// - f (*frame): current frame
+//
+// Returns:
+// - string: the converted value
+// - error: if an @include is malformed, its partial cannot be loaded
+// or the value holds another directive
+//
+// Since: 0.2.0
+func (g *generator) renderValue(text string, pos Pos, f *frame) (string, error) {
+	if name := valueDirective(text); name != "" {
+		return "", f.doc.errorf(pos, "@%s is not supported in attribute values, use an interpolation such as {{ if Active }}active{{ end }}", name)
+	}
+
+	var b strings.Builder
+
+	for {
+		start := strings.Index(text, "@include(")
+		if start > 0 && isWordChar(text[start-1]) {
+			rendered, err := f.renderInline(text[:start+1], pos)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(rendered)
+			text = text[start+1:]
+			continue
+		}
+		if start < 0 {
+			break
+		}
+
+		open := start + len("@include")
+		end := findParenEnd(text, open)
+		if end < 0 {
+			return "", f.doc.errorf(pos, "unclosed arguments of @include")
+		}
+
+		name, data, err := parseIncludeArguments(text[open+1 : end-1])
+		if err != nil {
+			return "", f.doc.errorf(pos, "malformed @include: %s", err.Error())
+		}
+
+		rendered, err := f.renderInline(text[:start], pos)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(rendered)
+		if err := g.renderInclude(&b, pos, name, data, f); err != nil {
+			return "", err
+		}
+		text = text[end:]
+	}
+
+	rendered, err := f.renderInline(text, pos)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(rendered)
+	return b.String(), nil
+}
+
+// Escapes the {{ of a text so go templates print it as is.
+// A string action such as {{ "{{" }} would be escaped in
+// styles and scripts, so the braces are split by a comment
+// trimming the space before it
+// ex: {{ x }} -> { {{- /* */}}{ x }}
+//
+// Params:
+// - text (string): the text
+//
+// Returns:
+// - string: the escaped text
+//
+// Since: 0.2.0
+func escapeBraces(text string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		b.WriteByte(text[i])
+		if text[i] == '{' && i+1 < len(text) && text[i+1] == '{' {
+			b.WriteString(" {{- /* */}}")
+		}
+	}
+
+	return b.String()
+}
+
+// Finds the first directive other than @include
+// in an attribute value, skipping interpolations
+// ex: btn @if Active active @end -> if
+//
+// Params:
+// - text (string): the value
+//
+// Returns:
+// - string: name of the directive, empty if there is none
+//
+// Since: 0.2.0
+func valueDirective(text string) string {
+	for i := 0; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], "{{") {
+			if end := findExpressionEnd(text, i); end > 0 {
+				i = end - 1
+				continue
+			}
+		}
+
+		if text[i] != '@' || (i > 0 && isWordChar(text[i-1])) || !isDirectiveAt(text, i) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(text) && isLetter(text[end]) {
+			end++
+		}
+		if name := text[i+1 : end]; name != "include" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Renders a component by expanding its file
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - b (*strings.Builder): output
+// - n (*ComponentNode): the component
+// - f (*frame): current frame
+//
+// Returns:
+// - error: if the component cannot be loaded
+//
+// Since: 0.2.0
+func (g *generator) renderComponent(b *strings.Builder, n *ComponentNode, f *frame) error {
+	if len(g.roots) == 0 {
+		return g.renderElement(b, &n.ElementNode, f)
+	}
+
+	if !isValidComponentName(n.Component) {
+		return withChain(f.doc.errorf(n.Pos, "invalid component name <%s>, a namespace is empty", n.Name), f.chain)
+	}
+
+	key, doc, err := g.findComponent(n.Name)
+	if errors.Is(err, fs.ErrNotExist) && g.options.AllowUnknownComponents {
+		return g.renderElement(b, &n.ElementNode, f)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		var unknown error
+		if suggestions := g.suggestComponents(n.Name); len(suggestions) > 0 {
+			unknown = f.doc.errorf(n.Pos, "unknown component <%s>, did you mean %s?", n.Name, joinOr(suggestions))
+		} else {
+			unknown = f.doc.errorf(n.Pos, "unknown component <%s>, %s does not exist", n.Name, key.path)
+		}
+		unknown.(*Error).Err = err
+		return withChain(unknown, f.chain)
+	}
+
+	if slices.Contains(f.components, key) {
+		return withChain(f.doc.errorf(n.Pos, "component <%s> includes itself", n.Name), f.chain)
+	}
+
+	chain := append(append([]string{}, f.chain...), g.componentFileName(key))
+	components := append(append([]docKey{}, f.components...), key)
+
+	if err == nil && doc.Extends != nil {
+		err = doc.errorf(doc.Extends.Pos, "components cannot use @extends")
+	}
+	if err != nil {
+		return withChain(err, chain)
+	}
+
+	attrs, props, err := g.bindProps(n, doc, f, newScope(g.root))
+	if err != nil {
+		return withChain(err, f.chain)
+	}
+
+	// Props read from the caller's data are evaluated once, before
+	// blocks inside the component change what the names refer to
+	if doc.Props != nil {
+		for _, prop := range doc.Props.Props {
+			operand, _ := props.lookup(prop.Name)
+			if readsDot(operand) {
+				name := g.variable(prop.Name)
+				fmt.Fprintf(b, "{{ %s := %s }}", name, operand)
+				props.set(prop.Name, name)
+			}
+		}
+	}
+
+	// Slot content rendered where the component changed the
+	// dot still reads the caller's data, kept in a variable
+	caller := f
+	slots := splitSlots(n.Children)
+	if len(slots) > 0 && f.scope.lookupDot() == "." && slotChangesDot(doc.Nodes, false) {
+		name := g.variable("dot")
+		fmt.Fprintf(b, "{{ %s := . }}", name)
+
+		data := *f
+		data.scope = newScope(f.scope)
+		data.scope.dot = name
+		caller = &data
+	}
+
+	return g.render(b, doc.Nodes, &frame{
+		doc:        doc,
+		attrs:      attrs,
+		slots:      slots,
+		parent:     caller,
+		chain:      chain,
+		components: components,
+		scope:      props,
+	})
+}
+
+// Reports whether a <slot /> is rendered inside of
+// an @for or @with block, where the dot is no longer
+// the data of the component's caller
+//
+// Params:
+// - nodes ([]Node): content of the component
+// - inBlock (bool): nodes are inside of such a block
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func slotChangesDot(nodes []Node, inBlock bool) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ElementNode:
+			if n.Name == "slot" && inBlock {
+				return true
+			}
+			if slotChangesDot(n.Children, inBlock) {
+				return true
+			}
+		case *ComponentNode:
+			if slotChangesDot(n.Children, inBlock) {
+				return true
+			}
+		case *IfNode:
+			for _, branch := range n.Branches {
+				if slotChangesDot(branch.Body, inBlock) {
+					return true
+				}
+			}
+			if n.Else != nil && slotChangesDot(n.Else.Body, inBlock) {
+				return true
+			}
+		case *SwitchNode:
+			for _, branch := range n.Cases {
+				if slotChangesDot(branch.Body, inBlock) {
+					return true
+				}
+			}
+			if n.Default != nil && slotChangesDot(n.Default.Body, inBlock) {
+				return true
+			}
+		case *ForNode:
+			if slotChangesDot(n.Body, true) {
+				return true
+			}
+			if n.Empty != nil && slotChangesDot(n.Empty.Body, inBlock) {
+				return true
+			}
+		case *WithNode:
+			if slotChangesDot(n.Body, true) {
+				return true
+			}
+			if n.Else != nil && slotChangesDot(n.Else.Body, inBlock) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Splits the attributes of a component into the props
+// it declares and plain attributes for @attributes.
+// Props are checked against their declared type
+//
+// Receiver:
+// - g (*generator)
+//
+// Params:
+// - n (*ComponentNode): the component
+// - doc (*Document): the component file
+// - f (*frame): frame of the caller
+// - props (*scope): scope to bind the props in
+//
+// Returns:
+// - Attributes: attributes that are not props
+// - *scope: the props scope
+// - error: if a prop is missing or has the wrong type
+//
+// Since: 0.2.0
+func (g *generator) bindProps(n *ComponentNode, doc *Document, f *frame, props *scope) (Attributes, *scope, error) {
+	declared := make(map[string]*Prop)
+	if doc.Props != nil {
+		for _, prop := range doc.Props.Props {
+			declared[prop.Name] = prop
+		}
+	}
+
+	attrs := make(Attributes)
+
+	for _, attr := range n.Attrs {
+		if attr.Kind != AttributeNormal && attr.Kind != AttributeBinding {
+			continue
+		}
+
+		prop, ok := declared[attr.Name]
+		switch {
+		case !ok && attr.Kind == AttributeBinding && booleanAttributes[strings.ToLower(attr.Name)]:
+			cond, err := f.expression(attr.Value, attr.Pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			attrs[attr.Name] = boundBoolean + cond
+			continue
+		case !ok && attr.Kind == AttributeBinding:
+			action, err := f.bindAttribute(attr.Value, attr.Pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			attrs[attr.Name] = action
+			continue
+		case !ok:
+			value, err := g.renderValue(attr.Value, attr.Pos, f)
+			if err != nil {
+				return nil, nil, err
+			}
+			attrs[attr.Name] = value
+			continue
+		case attr.Kind == AttributeBinding:
+			// Bound values are only known when the template runs
+			operand, err := f.operand(attr.Value, attr.Pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			props.set(prop.Name, operand)
+			continue
+		}
+
+		value := attr.Value
+		if !attr.HasValue && prop.Type == "bool" {
+			value = "true"
+		}
+
+		if strings.Contains(value, "{{") {
+			if prop.Type != "string" {
+				return nil, nil, f.doc.errorf(attr.Pos, "prop %s of <%s> must be a literal %s", prop.Name, n.Name, prop.typeString())
+			}
+			operand, err := inlineOperand(value, f.scope)
+			if err != nil {
+				return nil, nil, f.expressionError(value, attr.Pos, err)
+			}
+			props.set(prop.Name, operand)
+			continue
+		}
+
+		operand, err := prop.operand(value)
+		if err != nil {
+			return nil, nil, f.doc.errorf(attr.Pos, "prop %s of <%s> %s", prop.Name, n.Name, err.Error())
+		}
+		props.set(prop.Name, operand)
+	}
+
+	if doc.Props == nil {
+		return attrs, props, nil
+	}
+
+	for _, prop := range doc.Props.Props {
+		if _, ok := props.lookup(prop.Name); ok {
+			continue
+		}
+		if !prop.HasDefault {
+			return nil, nil, f.doc.errorf(n.Pos, "missing required prop %s of <%s>", prop.Name, n.Name)
+		}
+
+		operand, _ := prop.operand(prop.Default)
+		props.set(prop.Name, operand)
+	}
+
+	return attrs, props, nil
+}
+
+// Reports whether a go template operand reads
+// from the current data
+//
+// Params:
+// - operand (string): the operand
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func readsDot(operand string) bool {
+	tokens, err := lexExpr(operand)
+	if err != nil {
+		return true
+	}
+
+	for _, t := range tokens {
+		if t.typ == exprField {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the content of a wrapped component by slot.
+// Top level <template slot="name"> elements fill named
+// slots, everything else fills the default slot
+//
+// Params:
+// - children ([]Node): content of the component
+//
+// Returns:
+// - map[string][]Node: content by slot name
+//
+// Since: 0.2.0
+func splitSlots(children []Node) map[string][]Node {
+	slots := make(map[string][]Node)
+
+	for _, child := range children {
+		if el, ok := child.(*ElementNode); ok && el.Name == "template" {
+			if name := attributeValue(el.Attrs, "slot"); name != "" {
+				slots[name] = append(slots[name], el.Children...)
+				continue
+			}
+		}
+		slots[""] = append(slots[""], child)
+	}
+
+	return slots
+}
+
+// Reports whether nodes contain nothing but whitespace
+//
+// Params:
+// - nodes ([]Node): the nodes
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isBlank(nodes []Node) bool {
+	for _, node := range nodes {
+		text, ok := node.(*TextNode)
+		if !ok || strings.TrimSpace(text.Text) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Converts the expression of a :name="expression"
+// attribute to the action printing its value
+//
+// Receiver:
+// - f (*frame)
+//
+// Params:
+// - expr (string): the bound expression
+// - pos (Pos): position of the attribute
+//
+// Returns:
+// - string: the action
+// ex: {{ .User.ProfileURL }}
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func (f *frame) bindAttribute(expr string, pos Pos) (string, error) {
+	pipeline, err := f.expression(expr, pos)
+	if err != nil {
+		return "", err
+	}
+
+	return "{{ " + pipeline + " }}", nil
+}
+
+// Converts the interpolations inside of
+// a text such as an attribute value
+//
+// Receiver:
+// - f (*frame)
+//
+// Params:
+// - text (string): the text
+// - pos (Pos): position of the attribute
+//
+// Returns:
+// - string: the converted text
+// - error: if an interpolation is malformed
+//
+// Since: 0.2.0
+func (f *frame) renderInline(text string, pos Pos) (string, error) {
+	var b strings.Builder
+
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			break
+		}
+		end := findExpressionEnd(text, start)
+		if end < 0 {
+			break
+		}
+
+		action, err := translateInterpolation(text[start+2:end-2], text[start:end], f.scope)
+		if err != nil {
+			return "", f.expressionError(text[start+2:end-2], pos, err)
+		}
+
+		b.WriteString(text[:start])
+		b.WriteString(action)
+		text = text[end:]
+	}
+
+	b.WriteString(text)
+	return b.String(), nil
+}
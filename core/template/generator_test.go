@@ -0,0 +1,801 @@
+package template
+
+import (
+	htmltemplate "html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compileTestPage(t *testing.T, page string, components map[string]string) (string, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{"page.lamb.html": page}
+	for name, content := range components {
+		files[filepath.Join("components", name+".lamb.html")] = content
+	}
+	writeLambFiles(t, dir, files)
+
+	return ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+}
+
+func generateTestSource(t *testing.T, src string) (string, error) {
+	t.Helper()
+
+	doc, err := Parse("", src)
+	if err != nil {
+		return "", err
+	}
+
+	return newGenerator(Options{}).generate(doc)
+}
+
+func TestNamedSlots(t *testing.T) {
+	page := `<ui-layout>
+<template slot="header"><h1>{{ Title }}</h1></template>
+<p>Body</p>
+<template slot="footer">Bye</template>
+</ui-layout>`
+
+	layout := `<header><slot name="header" /></header><main><slot /></main><footer><slot name="footer" /></footer><aside><slot name="sidebar" /></aside>`
+
+	result, err := compileTestPage(t, page, map[string]string{"layout": layout})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<header><h1>{{ .Title }}</h1></header><main>\n\n<p>Body</p>\n\n</main><footer>Bye</footer><aside></aside>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestNamedSlotsOnlyAtTopLevel(t *testing.T) {
+	page := `<ui-box><div><template slot="header">nested</template></div></ui-box>`
+
+	result, err := compileTestPage(t, page, map[string]string{"box": `<slot name="header" />|<slot />`})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `|<div><template slot="header">nested</template></div>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestSlotFallbackContent(t *testing.T) {
+	button := `<button><slot>Submit</slot><slot name="icon" /></button>`
+
+	examples := map[string]string{
+		`<ui-button />`:               `<button>Submit</button>`,
+		`<ui-button>  </ui-button>`:   `<button>Submit</button>`,
+		`<ui-button>Save</ui-button>`: `<button>Save</button>`,
+		`<ui-button><template slot="icon">+</template></ui-button>`: `<button>Submit+</button>`,
+	}
+
+	for page, expected := range examples {
+		result, err := compileTestPage(t, page, map[string]string{"button": button})
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if result != expected {
+			t.Errorf("Expected %q, but got %q", expected, result)
+		}
+	}
+}
+
+func TestEmptySlotRemovedFromSelfClosingComponent(t *testing.T) {
+	container := `<div class="container"><slot /></div>`
+
+	result, err := compileTestPage(t, `<ui-container />`, map[string]string{"container": container})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="container"></div>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestComponentProps(t *testing.T) {
+	card := "@props(title: string, count: int = 0, variant: \"primary\"|\"secondary\" = \"primary\")\n<div class=\"card-{{ variant }}\" @attributes()>{{ title }} ({{ count }})</div>"
+
+	result, err := compileTestPage(t, `<ui-card title="Hello {{ name }}" count="3" id="main" />`, map[string]string{"card": card})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `{{ $title := (print "Hello " .name) }}<div class="card-{{ "primary" }}" id="main">{{ $title }} ({{ 3 }})</div>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestComponentPropErrors(t *testing.T) {
+	card := "@props(title: string, count: int = 0, variant: \"primary\"|\"secondary\" = \"primary\")\n<div>{{ title }}</div>"
+
+	examples := map[string]string{
+		"<p>\n  <ui-card count=\"2\" />\n</p>":      "page.lamb.html:2:3: missing required prop title of <ui-card>",
+		`<ui-card title="A" count="many" />`:        `page.lamb.html:1:20: prop count of <ui-card> must be an int, got "many"`,
+		`<ui-card title="A" variant="danger" />`:    `page.lamb.html:1:20: prop variant of <ui-card> must be one of "primary"|"secondary", got "danger"`,
+		`<ui-card title="A" count="{{ Count }}" />`: `page.lamb.html:1:20: prop count of <ui-card> must be a literal int`,
+	}
+
+	for page, expected := range examples {
+		_, err := compileTestPage(t, page, map[string]string{"card": card})
+		if err == nil {
+			t.Errorf("Expected an error for %q, but got none", page)
+		} else if !strings.HasSuffix(err.Error(), expected) {
+			t.Errorf("Expected error message ending with '%s', but got '%s'", expected, err.Error())
+		}
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	card := "@props(title: string)\n<div>{{ title }}</div>"
+
+	examples := map[string]string{
+		"<p>\n@if Count >\nyes\n@end\n</p>":     `page.lamb.html:2:1: malformed expression "Count >": unexpected end of expression`,
+		"<p>{{ (User.Name }}</p>":               `page.lamb.html:1:4: malformed expression "(User.Name": missing )`,
+		"@for item in Items)\n{{ item }}\n@end": `page.lamb.html:1:1: malformed expression "Items)": unexpected )`,
+		`<a :href="Base +">x</a>`:               `page.lamb.html:1:4: malformed expression "Base +"`,
+		`<ui-card :title="A and" />`:            `page.lamb.html:1:10: malformed expression "A and"`,
+		`<b>@switch Kind @case "a" A @end</b>`:  `page.lamb.html:1:17: malformed expression "\"a\" A": unexpected A, put the arguments in parentheses when content follows them on the line`,
+		`<ui-card title="Hi {{ A or }}" />`:     `page.lamb.html:1:10: malformed expression "Hi {{ A or }}"`,
+		"<ul>\n@for i, u in Users x\n@end</ul>": `page.lamb.html:2:1: malformed expression "Users x": unknown function Users, call it as Users(...) or register it in Funcs`,
+		"@if A x @end":                          `page.lamb.html:1:1: malformed expression "A x": unknown function A`,
+	}
+
+	for page, expected := range examples {
+		_, err := compileTestPage(t, page, map[string]string{"card": card})
+		if err == nil {
+			t.Errorf("Expected an error for %q, but got none", page)
+		} else if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error message containing '%s', but got '%s'", expected, err.Error())
+		}
+	}
+}
+
+func TestAttributeValueDirectives(t *testing.T) {
+	examples := map[string]string{
+		`<div class="btn @if Active active @end">x</div>`:         `page.lamb.html:1:6: @if is not supported in attribute values`,
+		"<p>\n<ui-card title=\"@for x in Items {{ x }} @end\" />": `page.lamb.html:2:10: @for is not supported in attribute values`,
+	}
+
+	for page, expected := range examples {
+		_, err := compileTestPage(t, page, map[string]string{"card": "<div></div>"})
+		if err == nil {
+			t.Errorf("Expected an error for %q, but got none", page)
+		} else if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error message containing '%s', but got '%s'", expected, err.Error())
+		}
+	}
+
+	result, err := generateTestSource(t, `<a href="mailto:me@end.com" title="{{ "@if" }}">x</a>`)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<a href="mailto:me@end.com" title="{{ "@if" }}">x</a>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestAttributesDirectiveMerge(t *testing.T) {
+	examples := []struct {
+		button   string
+		page     string
+		expected string
+	}{
+		// Adds an id
+		{
+			`<button @attributes("id": "btn")>Submit</button>`,
+			`<ui-button class="btn primary" type="submit" />`,
+			`<button class="btn primary" id="btn" type="submit">Submit</button>`,
+		},
+		// The caller replaces the id
+		{
+			`<button @attributes("id": "oldID")>Submit</button>`,
+			`<ui-button class="btn primary" id="newID" />`,
+			`<button class="btn primary" id="newID">Submit</button>`,
+		},
+		// Classes are joined, the caller's first
+		{
+			`<button @attributes("class": "original classes")>Submit</button>`,
+			`<ui-button class="newClass" type="submit" />`,
+			`<button class="newClass original classes" type="submit">Submit</button>`,
+		},
+		{
+			`<button @attributes("class": "original classes", "id": "oldID")>Submit</button>`,
+			`<ui-button class="newClass" id="newID" type="submit" />`,
+			`<button class="newClass original classes" id="newID" type="submit">Submit</button>`,
+		},
+		// Without arguments only the caller's attributes are written
+		{
+			`<button @attributes()>Submit</button>`,
+			`<ui-button id="newID" />`,
+			`<button id="newID">Submit</button>`,
+		},
+		{
+			`<button @attributes()>Submit</button>`,
+			`<ui-button class="newClass" />`,
+			`<button class="newClass">Submit</button>`,
+		},
+		{
+			`<button @attributes()>Submit</button>`,
+			`<ui-button class="newClass" id="newID" type="submit" />`,
+			`<button class="newClass" id="newID" type="submit">Submit</button>`,
+		},
+	}
+
+	for _, example := range examples {
+		result, err := compileTestPage(t, example.page, map[string]string{"button": example.button})
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if result != example.expected {
+			t.Errorf("Expected %q, but got %q", example.expected, result)
+		}
+	}
+}
+
+func TestOneLineDirectives(t *testing.T) {
+	examples := map[string]string{
+		`<p>@if(A)Yes@else No@end</p>`:                             "<p>{{ if .A }}Yes{{ else }} No{{ end }}</p>",
+		`<p>@if A <b>x</b>@end</p>`:                                "<p>{{ if .A }}<b>x</b>{{ end }}</p>",
+		`<p class="{{ Cls }}">@if A {{ x }} @end</p>`:              "<p class=\"{{ .Cls }}\">{{ if .A }}{{ .x }} {{ end }}</p>",
+		`<ul>@for item in Items<li>{{ item }}</li>@end</ul>`:       "<ul>{{ range $item := .Items }}<li>{{ $item }}</li>{{ end }}</ul>",
+		`<i>@if Name == "a@if b" @else none@end</i>`:               "<i>{{ if eq .Name \"a@if b\" }}{{ else }} none{{ end }}</i>",
+		`<b>@switch(Kind)@case("a")A@case("b")B@default C@end</b>`: "<b>{{ if eq .Kind \"a\" }}A{{ else if eq .Kind \"b\" }}B{{ else }} C{{ end }}</b>",
+		"mail me@end.com":                                          "mail me@end.com",
+		"@if(A) me@end.com @end":                                   "{{ if .A }} me@end.com {{ end }}",
+	}
+
+	for example, expected := range examples {
+		result, err := generateTestSource(t, example)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, but got error: %s", example, err.Error())
+		}
+
+		if result != expected {
+			t.Errorf("Expected %q, but got %q", expected, result)
+		}
+	}
+}
+
+func TestTemplateActions(t *testing.T) {
+	example := `<p class="btn {{ if Active }}active{{ end }}">{{ with User }}{{ Name }}{{ else }}none{{ end }}</p>{{ range $i, $u := Users }}{{ $u.Name }}{{ end }}`
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<p class="btn {{ if .Active }}active{{ end }}">{{ with .User }}{{ .Name }}{{ else }}none{{ end }}</p>{{ range $i, $u := .Users }}{{ $u.Name }}{{ end }}`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestAttributeBinding(t *testing.T) {
+	link := "@props(label: string)\n<a @attributes(\"class\": \"underline\")>{{ label }}</a>"
+
+	page := `<img :src="User.Avatar" /><ui-link :href="User.ProfileURL" :class="Theme" :label="User.Name" />`
+
+	result, err := compileTestPage(t, page, map[string]string{"link": link})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<img src="{{ .User.Avatar }}" />{{ $label := .User.Name }}<a class="{{ .Theme }} underline" href="{{ .User.ProfileURL }}">{{ $label }}</a>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestBooleanAttributeBinding(t *testing.T) {
+	example := `<button :disabled="Off" :title="Hint">Save</button><input type="checkbox" :checked="!Unread" />`
+
+	expected := `<button {{ if .Off }}disabled{{ end }} title="{{ .Hint }}">Save</button><input type="checkbox" {{ if not .Unread }}checked{{ end }} />`
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestBooleanAttributeBindingThroughComponent(t *testing.T) {
+	checkbox := `<input type="checkbox" @attributes("class": "check") />`
+
+	page := `<ui-checkbox :checked="On" :disabled="!Editable" :title="Hint" />`
+
+	result, err := compileTestPage(t, page, map[string]string{"checkbox": checkbox})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<input type="checkbox" {{ if .On }}checked{{ end }} class="check" {{ if not .Editable }}disabled{{ end }} title="{{ .Hint }}" />`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+
+	tmpl, err := htmltemplate.New("page").Parse(result)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]any{"On": false, "Editable": true, "Hint": "Agree"}); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	rendered := `<input type="checkbox"  class="check"  title="Agree" />`
+	if b.String() != rendered {
+		t.Errorf("Expected %q, but got %q", rendered, b.String())
+	}
+}
+
+func TestForLoopVariables(t *testing.T) {
+	examples := map[string]string{
+		"@for item in Items\n{{ item.Name }}\n@end":                                 "{{ range $item := .Items }}\n{{ $item.Name }}\n{{ end }}",
+		"@for i, item in Items\n{{ i }}: {{ item }} {{ Title }}\n@end":              "{{ range $i, $item := .Items }}\n{{ $i }}: {{ $item }} {{ .Title }}\n{{ end }}",
+		"@for k, v in Map\n@if v > 1\n{{ k }}\n@end\n@end":                          "{{ range $k, $v := .Map }}\n{{ if gt $v 1 }}\n{{ $k }}\n{{ end }}\n{{ end }}",
+		"@for user in Users\n{{ user }}\n@empty\nNo users\n@end":                    "{{ range $user := .Users }}\n{{ $user }}\n{{ else }}\nNo users\n{{ end }}",
+		"@for row in Rows\n@for cell in row.Cells\n{{ cell }}{{ row }}\n@end\n@end": "{{ range $row := .Rows }}\n{{ range $cell := $row.Cells }}\n{{ $cell }}{{ $row }}\n{{ end }}\n{{ end }}",
+	}
+
+	for example, expected := range examples {
+		result, err := generateTestSource(t, example)
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+		if result != expected {
+			t.Errorf("Expected %q, but got %q", expected, result)
+		}
+	}
+}
+
+func TestForLoopVariablesInSlots(t *testing.T) {
+	card := "@props(title: string)\n<div>{{ title }}<slot /></div>"
+
+	page := "@for item in Items\n<ui-card :title=\"item.Name\">{{ item.Price }}</ui-card>\n@end"
+
+	result, err := compileTestPage(t, page, map[string]string{"card": card})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ range $item := .Items }}\n<div>{{ $item.Name }}{{ $item.Price }}</div>\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestForLoopVariablesNotShadowedByProps(t *testing.T) {
+	tag := "@props(item: string)\n<b>{{ item }}</b>"
+
+	page := "@for item in Items\n<ui-tag :item=\"Title\" />{{ item }}\n@end"
+
+	result, err := compileTestPage(t, page, map[string]string{"tag": tag})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ range $item := .Items }}\n{{ $item_2 := .Title }}<b>{{ $item_2 }}</b>{{ $item }}\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestSlotContentReadsCallerData(t *testing.T) {
+	panel := "<section>@with(Settings)<div>{{ Theme }}<slot /></div>@end</section>"
+
+	page := "<ui-panel><p>{{ User.Name }} {{ .Raw }}</p>@with(User)<i>{{ Name }}</i>@end</ui-panel>"
+
+	result, err := compileTestPage(t, page, map[string]string{"panel": panel})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ $dot := . }}<section>{{ with .Settings }}<div>{{ .Theme }}<p>{{ $dot.User.Name }} {{ $dot.Raw }}</p>{{ with $dot.User }}<i>{{ .Name }}</i>{{ end }}</div>{{ end }}</section>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	example := "@switch User.Status\n@case \"active\"\nActive\n@case \"banned\", \"suspended\"\nBlocked\n@default\nUnknown\n@end"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ if eq .User.Status \"active\" }}\nActive\n{{ else if eq .User.Status \"banned\" \"suspended\" }}\nBlocked\n{{ else }}\nUnknown\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestSwitchOnExpression(t *testing.T) {
+	example := "@for item in Items\n@switch len item.Tags\n@case 0\nNone\n@case Max\nFull\n@end\n@end"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ range $item := .Items }}\n{{ if eq (len $item.Tags) 0 }}\nNone\n{{ else if eq (len $item.Tags) .Max }}\nFull\n{{ end }}\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestWithAndLet(t *testing.T) {
+	example := "@let total = Order.Total\n@with Order.Customer\n{{ Name }} owes {{ total }}\n@else\nNo customer\n@end"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ $total := .Order.Total }}\n{{ with .Order.Customer }}\n{{ .Name }} owes {{ $total }}\n{{ else }}\nNo customer\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestLetScopedToBlock(t *testing.T) {
+	example := "@if A\n@let x = B\n{{ x }}\n@end\n{{ x }}\n@let x = C\n{{ x }}"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ if .A }}\n{{ $x := .B }}\n{{ $x }}\n{{ end }}\n{{ .x }}\n{{ $x_2 := .C }}\n{{ $x_2 }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestPropsKeepTheirValueInsideBlocks(t *testing.T) {
+	list := "@props(title: string)\n@with Items\n<h2>{{ title }}</h2>\n@let title = Name\n{{ title }}\n@end"
+
+	result, err := compileTestPage(t, `<ui-list title="{{ Title }}" /><ui-list :title="Title" />`, map[string]string{"list": list})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "{{ $title := .Title }}{{ with .Items }}\n<h2>{{ $title }}</h2>\n{{ $title_2 := .Name }}\n{{ $title_2 }}\n{{ end }}" +
+		"{{ $title_3 := .Title }}{{ with .Items }}\n<h2>{{ $title_3 }}</h2>\n{{ $title_4 := .Name }}\n{{ $title_4 }}\n{{ end }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestEscapedLambSyntax(t *testing.T) {
+	example := "Follow @@lamb\n@verbatim\n@if Vue <ui-card>{{ message }}</ui-card> @end\n@endverbatim\n{{ Name }}"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "Follow @lamb\n\n@if Vue <ui-card>{ {{- /* */}}{ message }}</ui-card> @end\n\n{{ .Name }}"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestRawTextIsNotProcessed(t *testing.T) {
+	example := "<script>if (a) { @if b }\nconst t = `{{ x }}`</script>\n<!-- <ui-card> @end {{ y }} -->\n<textarea>@else</textarea>\n<STYLE>a{}</STYLE>\n<p>{{ z }}</p>"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<script>if (a) { @if b }\nconst t = `{ {{- /* */}}{ x }}`</script>\n<!-- <ui-card> @end { {{- /* */}}{ y }} -->\n<textarea>@else</textarea>\n<STYLE>a{}</STYLE>\n<p>{{ .z }}</p>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestRawTextOptIn(t *testing.T) {
+	example := "<script lamb type=\"module\">const user = {{ User.Name }}\n@if Debug\nconsole.log(user)\n@end</script>"
+
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<script type=\"module\">const user = {{ .User.Name }}\n{{ if .Debug }}\nconsole.log(user)\n{{ end }}</script>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestCommentOptIn(t *testing.T) {
+	example := "<!--lamb {{ Note }} <ui-card /> -->\n<!--lamb-->\n<!-- {{ Skip }} -->"
+
+	result, err := compileTestPage(t, example, map[string]string{"card": "<b>card</b>"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<!-- {{ .Note }} <b>card</b> -->\n<!--lamb-->\n<!-- { {{- /* */}}{ Skip }} -->"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestLayoutInheritance(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html": `@extends("layouts/app")
+@section("title") Users @end
+@section("content")
+<ul>@for(user in users)<li>{{ user.Name }}</li>@end</ul>
+@end`,
+		"layouts/app.lamb.html": `<title>@yield("title", "Lamb")</title><main>@yield("content")</main><footer>@yield("footer", "Bye")</footer>`,
+	})
+
+	result, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<title> Users </title><main>\n<ul>{{ range $user := .users }}<li>{{ $user.Name }}</li>{{ end }}</ul>\n</main><footer>Bye</footer>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestNestedLayouts(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html": `@extends("layouts/admin")
+@section("title") Settings @end`,
+		"layouts/admin.lamb.html": `@extends("layouts/app")
+@section("title") Admin @end
+@section("content")<nav>Admin</nav><h1>@yield("title")</h1>@end`,
+		"layouts/app.lamb.html": `<title>@yield("title")</title><main>@yield("content")</main>`,
+	})
+
+	result, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := "<title> Settings </title><main><nav>Admin</nav><h1> Settings </h1></main>"
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	tests := map[string]string{
+		`@extends("layouts/missing")`:     "unknown layout layouts/missing",
+		`@extends("page")`:                "layout page extends itself",
+		`<ui-card />`:                     "components cannot use @extends",
+		"@extends(\"layouts/app\")\n<p>x": "content outside of @section in a file extending a layout",
+	}
+
+	for page, message := range tests {
+		dir := t.TempDir()
+		writeLambFiles(t, dir, map[string]string{
+			"page.lamb.html":            page,
+			"layouts/app.lamb.html":     `@yield("content")`,
+			"components/card.lamb.html": `@extends("layouts/app")`,
+		})
+
+		_, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected error containing %q for %q, but got %v", message, page, err)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":            `@for(item in Items)<nav class='@include("partials/active", item)'>@include("partials/nav", item.Menu)</nav>@end <footer>@include("partials/footer")</footer>`,
+		"partials/nav.lamb.html":    `@for(link in Links)<a href="{{ link.URL }}">{{ link.Title }}</a>@end`,
+		"partials/active.lamb.html": `@if(Active) active @end`,
+		"partials/footer.lamb.html": `&copy; {{ Year }}`,
+	})
+
+	result, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `{{ range $item := .Items }}<nav class='{{ template "partials/active" $item }}'>{{ template "partials/nav" $item.Menu }}</nav>{{ end }} <footer>{{ template "partials/footer" . }}</footer>` +
+		`{{ define "partials/active" }}{{ if .Active }} active {{ end }}{{ end }}` +
+		`{{ define "partials/nav" }}{{ range $link := .Links }}<a href="{{ $link.URL }}">{{ $link.Title }}</a>{{ end }}{{ end }}` +
+		`{{ define "partials/footer" }}&copy; {{ .Year }}{{ end }}`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestIncludeItself(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":          `@include("partials/tree", Root)`,
+		"partials/tree.lamb.html": `<li>{{ Name }}@for(child in Children)<ul>@include("partials/tree", child)</ul>@end</li>`,
+	})
+
+	result, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `{{ template "partials/tree" .Root }}{{ define "partials/tree" }}<li>{{ .Name }}{{ range $child := .Children }}<ul>{{ template "partials/tree" $child }}</ul>{{ end }}</li>{{ end }}`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := map[string]string{
+		`@include("partials/missing")`:             "page.lamb.html:1:1: unknown partial partials/missing",
+		`<p class="@include('partials/x', A, B)">`: "page.lamb.html:1:4: malformed @include",
+		`<p class="@include('partials/x'">`:        "page.lamb.html:1:4: unclosed arguments of @include",
+		`@include("partials/layout")`:              "layout.lamb.html:1:1: partials cannot use @extends",
+	}
+
+	for page, message := range tests {
+		dir := t.TempDir()
+		writeLambFiles(t, dir, map[string]string{
+			"page.lamb.html":            page,
+			"partials/layout.lamb.html": `@extends("app")`,
+		})
+
+		_, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected error containing %q for %q, but got %v", message, page, err)
+		}
+	}
+}
+
+func TestNamespacedComponents(t *testing.T) {
+	page := `<ui-forms.input name="email" /><ui-forms:input name="password" /><ui-card>Hi</ui-card><ui-forms.fields.text />`
+
+	result, err := compileTestPage(t, page, map[string]string{
+		"forms/input":        `<input @attributes() />`,
+		"card/index":         `<div class="card"><slot /></div>`,
+		"forms/fields/text":  `<textarea></textarea>`,
+		"forms/fields/index": `<fieldset></fieldset>`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<input name="email" /><input name="password" /><div class="card">Hi</div><textarea></textarea>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestComponentFilePreferredOverIndex(t *testing.T) {
+	result, err := compileTestPage(t, `<ui-card />`, map[string]string{
+		"card":       `<div class="file"></div>`,
+		"card/index": `<div class="index"></div>`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="file"></div>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestNamespacedComponentErrors(t *testing.T) {
+	tests := map[string]string{
+		`<ui-forms.missing />`: "page.lamb.html:1:1: unknown component <ui-forms.missing>, ",
+		`<ui-forms..input />`:  "page.lamb.html:1:1: invalid component name <ui-forms..input>, a namespace is empty",
+		`<ui-forms: />`:        "page.lamb.html:1:1: invalid component name <ui-forms:>, a namespace is empty",
+	}
+
+	for page, message := range tests {
+		_, err := compileTestPage(t, page, map[string]string{"forms/input": `<input />`})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected error containing %q for %q, but got %v", message, page, err)
+		}
+	}
+}
+
+func TestComponentPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":            `<x-card><ui-tooltip text="{{ Hint }}"><p>Hi</ui-tooltip></x-card><lamb-card /><ui-icon>`,
+		"components/card.lamb.html": `<div class="card"><slot /></div>`,
+	})
+
+	result, err := ParsePage(filepath.Join(dir, "page.lamb.html"), Options{
+		ComponentDir:      filepath.Join(dir, "components"),
+		ComponentPrefixes: []string{"x-", "lamb-"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="card"><ui-tooltip text="{{ .Hint }}"><p>Hi</ui-tooltip></div><div class="card"></div><ui-icon>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestUnknownComponentSuggestions(t *testing.T) {
+	components := map[string]string{
+		"form":        `<form></form>`,
+		"font":        `<b></b>`,
+		"forms/input": `<input />`,
+		"card/index":  `<div></div>`,
+	}
+
+	tests := map[string]string{
+		`<ui-fom />`:         "page.lamb.html:1:1: unknown component <ui-fom>, did you mean <ui-form> or <ui-font>?",
+		`<ui-forms.inptu />`: "page.lamb.html:1:1: unknown component <ui-forms.inptu>, did you mean <ui-forms.input>?",
+		`<ui-crad />`:        "page.lamb.html:1:1: unknown component <ui-crad>, did you mean <ui-card>?",
+		`<ui-modal />`:       "page.lamb.html:1:1: unknown component <ui-modal>, ",
+	}
+
+	for page, message := range tests {
+		_, err := compileTestPage(t, page, components)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected error containing %q for %q, but got %v", message, page, err)
+		}
+	}
+}
+
+func TestAllowUnknownComponents(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":            `<ui-card><ui-chart :data="Points">{{ Title }}</ui-chart></ui-card>`,
+		"components/card.lamb.html": `<div class="card"><slot /></div>`,
+	})
+
+	result, err := ParsePage(filepath.Join(dir, "page.lamb.html"), Options{
+		ComponentDir:           filepath.Join(dir, "components"),
+		AllowUnknownComponents: true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="card"><ui-chart data="{{ .Points }}">{{ .Title }}</ui-chart></div>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
+
+func TestNestedSameNameComponents(t *testing.T) {
+	page := `<ui-card title="outer"><ui-card title="middle"><ui-card title="inner">x</ui-card></ui-card><p>after</p></ui-card>`
+
+	result, err := compileTestPage(t, page, map[string]string{
+		"card": `<section @attributes()><slot /></section>`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<section title="outer"><section title="middle"><section title="inner">x</section></section><p>after</p></section>`
+	if result != expected {
+		t.Errorf("Expected %q, but got %q", expected, result)
+	}
+}
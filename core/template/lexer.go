@@ -0,0 +1,790 @@
+package template
+
+import (
+	"strings"
+)
+
+// Kind of a lexed token
+//
+// Since: 0.2.0
+type tokenType int
+
+const (
+	tokenText tokenType = iota
+	tokenExpression
+	tokenDirective
+	tokenStartTag
+	tokenEndTag
+	tokenVerbatim
+	tokenRawText
+)
+
+// Directives recognized outside of tags, mapped to
+// whether they take arguments up to the end of the line
+//
+// Since: 0.2.0
+var directives = map[string]bool{
+	"if":      true,
+	"elseif":  true,
+	"else":    false,
+	"for":     true,
+	"empty":   false,
+	"switch":  true,
+	"case":    true,
+	"default": false,
+	"with":    true,
+	"let":     true,
+	"extends": false,
+	"section": false,
+	"yield":   false,
+	"include": false,
+	"end":     false,
+	"props":   false,
+}
+
+// Directives opening a block closed by @end
+//
+// Since: 0.2.0
+var blockDirectives = map[string]bool{
+	"if":      true,
+	"for":     true,
+	"switch":  true,
+	"with":    true,
+	"section": true,
+}
+
+// Directives continuing or closing a block. Inside of a
+// block they may directly follow a word, as in Yes@else
+//
+// Since: 0.2.0
+var closingDirectives = map[string]bool{
+	"elseif":  true,
+	"else":    true,
+	"empty":   true,
+	"case":    true,
+	"default": true,
+	"end":     true,
+}
+
+// Elements that never have content or an end tag
+//
+// Since: 0.2.0
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"param":  true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+// Elements whose content is copied as is unless
+// they opt in with the processAttribute attribute
+//
+// Since: 0.2.0
+var rawTextElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+}
+
+// Attribute asking for lamb syntax to be processed
+// inside of a raw text element, also accepted right
+// after the start of a comment
+// ex: <script lamb>, <!--lamb {{ Note }} -->
+//
+// Since: 0.2.0
+const processAttribute = "lamb"
+
+// A single lexed token
+//
+// Fields:
+// - typ (tokenType): kind of token
+// - pos (Pos): offset of the token
+// - end (Pos): offset just past the token
+// - val (string): text, expression body, directive name,
+// tag name or verbatim text
+// - args (string): directive arguments
+// - parens (bool): directive arguments were written in parentheses
+// - attrs ([]*Attribute): start tag attributes
+// - space (string): whitespace before the end of a start tag
+// - selfClosing (bool): start tag ends with />
+//
+// Since: 0.2.0
+type token struct {
+	typ         tokenType
+	pos         Pos
+	end         Pos
+	val         string
+	args        string
+	parens      bool
+	attrs       []*Attribute
+	space       string
+	selfClosing bool
+}
+
+// Splits lamb source into tokens
+//
+// Fields:
+// - doc (*Document): document being lexed
+// - src (string): the source
+// - pos (int): current offset
+// - text (int): start of the pending text
+// - tokens ([]token): lexed tokens
+// - blocks (int): directive blocks opened and not yet closed
+//
+// Since: 0.2.0
+type lexer struct {
+	doc    *Document
+	src    string
+	pos    int
+	text   int
+	tokens []token
+	blocks int
+}
+
+// Lex the source of a document
+//
+// Params:
+// - doc (*Document): document to lex
+//
+// Returns:
+// - []token: the tokens
+// - error: if an interpolation is never closed
+//
+// Since: 0.2.0
+func lex(doc *Document) ([]token, error) {
+	l := &lexer{doc: doc, src: doc.Source}
+
+	for l.pos < len(l.src) {
+		next := strings.IndexAny(l.src[l.pos:], "{@<")
+		if next < 0 {
+			break
+		}
+		l.pos += next
+
+		switch l.src[l.pos] {
+		case '{':
+			if err := l.lexExpression(); err != nil {
+				return nil, err
+			}
+		case '@':
+			if err := l.lexDirective(); err != nil {
+				return nil, err
+			}
+		case '<':
+			l.lexTag()
+		}
+	}
+
+	l.pos = len(l.src)
+	l.emitText()
+	return l.tokens, nil
+}
+
+// Emits the text between the last token and
+// the current position
+//
+// Receiver:
+// - l (*lexer)
+//
+// Since: 0.2.0
+func (l *lexer) emitText() {
+	if l.text < l.pos {
+		l.tokens = append(l.tokens, token{
+			typ: tokenText,
+			pos: Pos(l.text),
+			end: Pos(l.pos),
+			val: l.src[l.text:l.pos],
+		})
+	}
+}
+
+// Emits a token ending at end and moves past it
+//
+// Receiver:
+// - l (*lexer)
+//
+// Params:
+// - t (token): the token, pos is filled in
+// - end (int): offset just past the token
+//
+// Since: 0.2.0
+func (l *lexer) emit(t token, end int) {
+	l.emitText()
+	t.pos = Pos(l.pos)
+	t.end = Pos(end)
+	l.tokens = append(l.tokens, t)
+	l.pos = end
+	l.text = end
+}
+
+// Lex a {{ }} interpolation
+//
+// Receiver:
+// - l (*lexer)
+//
+// Returns:
+// - error: if the interpolation is never closed
+//
+// Since: 0.2.0
+func (l *lexer) lexExpression() error {
+	if !strings.HasPrefix(l.src[l.pos:], "{{") {
+		l.pos++
+		return nil
+	}
+
+	end := findExpressionEnd(l.src, l.pos)
+	if end < 0 {
+		return l.doc.errorf(Pos(l.pos), "unclosed {{")
+	}
+
+	l.emit(token{typ: tokenExpression, val: l.src[l.pos+2 : end-2]}, end)
+	return nil
+}
+
+// Lex an @ directive. Unknown directives and @ signs
+// inside words are left as text, except for directives
+// closing an open block. @@ is an escaped @. Arguments
+// without parentheses end with the line, or before a
+// tag, an interpolation or another directive
+//
+// Receiver:
+// - l (*lexer)
+//
+// Returns:
+// - error: if parenthesized arguments or
+// a @verbatim block are never closed
+//
+// Since: 0.2.0
+func (l *lexer) lexDirective() error {
+	if strings.HasPrefix(l.src[l.pos:], "@@") {
+		l.emit(token{typ: tokenVerbatim, val: "@"}, l.pos+2)
+		return nil
+	}
+
+	nameEnd := l.pos + 1
+	for nameEnd < len(l.src) && isLetter(l.src[nameEnd]) {
+		nameEnd++
+	}
+	name := l.src[l.pos+1 : nameEnd]
+
+	// Words such as e-mail addresses stay text
+	if l.pos > 0 && isWordChar(l.src[l.pos-1]) {
+		closing := l.blocks > 0 && closingDirectives[name]
+		if !closing || (nameEnd < len(l.src) && l.src[nameEnd] == '.') {
+			l.pos++
+			return nil
+		}
+	}
+
+	if name == "verbatim" && (nameEnd >= len(l.src) || !isWordChar(l.src[nameEnd])) {
+		return l.lexVerbatim(nameEnd)
+	}
+
+	takesArgs, ok := directives[name]
+	if !ok || (nameEnd < len(l.src) && isWordChar(l.src[nameEnd])) {
+		l.pos = nameEnd
+		return nil
+	}
+
+	t := token{typ: tokenDirective, val: name}
+	end := nameEnd
+
+	if nameEnd < len(l.src) && l.src[nameEnd] == '(' {
+		close := findParenEnd(l.src, nameEnd)
+		if close < 0 {
+			return l.doc.errorf(Pos(l.pos), "unclosed arguments of @%s", name)
+		}
+		t.args = strings.TrimSpace(l.src[nameEnd+1 : close-1])
+		t.parens = true
+		end = close
+	} else if takesArgs {
+		end = argumentsEnd(l.src, nameEnd)
+		t.args = strings.TrimSpace(l.src[nameEnd:end])
+	}
+
+	switch {
+	case blockDirectives[name]:
+		l.blocks++
+	case name == "end" && l.blocks > 0:
+		l.blocks--
+	}
+
+	l.emit(t, end)
+	return nil
+}
+
+// Finds the end of directive arguments written
+// without parentheses: the end of the line, or the
+// start of a tag, an interpolation or a directive
+// outside of quotes
+//
+// Params:
+// - src (string): the source
+// - start (int): offset just past the directive name
+//
+// Returns:
+// - int: offset just past the arguments
+//
+// Since: 0.2.0
+func argumentsEnd(src string, start int) int {
+	var quote byte
+
+	for i := start; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '\n' || c == '\r':
+			return i
+		case c == '<' && i+1 < len(src) && (isLetter(src[i+1]) || src[i+1] == '/' || src[i+1] == '!'):
+			return i
+		case c == '{' && strings.HasPrefix(src[i:], "{{"):
+			return i
+		case c == '@' && isDirectiveAt(src, i):
+			return i
+		}
+	}
+
+	return len(src)
+}
+
+// Reports whether a known directive starts at an
+// offset, not followed by more word characters
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the @
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isDirectiveAt(src string, pos int) bool {
+	end := pos + 1
+	for end < len(src) && isLetter(src[end]) {
+		end++
+	}
+
+	if end < len(src) && isWordChar(src[end]) {
+		return false
+	}
+
+	_, ok := directives[src[pos+1:end]]
+	return ok || src[pos+1:end] == "verbatim"
+}
+
+// Lex a @verbatim block, whose content is
+// kept exactly as written
+//
+// Receiver:
+// - l (*lexer)
+//
+// Params:
+// - start (int): offset just past @verbatim
+//
+// Returns:
+// - error: if @endverbatim is missing
+//
+// Since: 0.2.0
+func (l *lexer) lexVerbatim(start int) error {
+	end := strings.Index(l.src[start:], "@endverbatim")
+	if end < 0 {
+		return l.doc.errorf(Pos(l.pos), "unclosed @verbatim, missing @endverbatim")
+	}
+	end += start
+
+	l.emit(token{typ: tokenVerbatim, val: l.src[start:end]}, end+len("@endverbatim"))
+	return nil
+}
+
+// Lex a start or end tag. Anything that does not
+// form a valid tag is left as text, as are html
+// comments and the content of raw text elements.
+// Comments opening with <!--lamb are processed
+//
+// Receiver:
+// - l (*lexer)
+//
+// Since: 0.2.0
+func (l *lexer) lexTag() {
+	rest := l.src[l.pos:]
+
+	if strings.HasPrefix(rest, "<!--") {
+		marker := "<!--" + processAttribute
+		if strings.HasPrefix(rest, marker) && len(rest) > len(marker) && isSpace(rest[len(marker)]) {
+			l.emit(token{typ: tokenRawText, val: marker}, l.pos+len(marker))
+			return
+		}
+
+		end := len(l.src)
+		if i := strings.Index(rest[4:], "-->"); i >= 0 {
+			end = l.pos + 4 + i + len("-->")
+		}
+		l.emit(token{typ: tokenRawText, val: l.src[l.pos:end]}, end)
+		return
+	}
+
+	if strings.HasPrefix(rest, "</") && len(rest) > 2 && isLetter(rest[2]) {
+		close := strings.IndexByte(rest, '>')
+		if close < 0 {
+			l.pos++
+			return
+		}
+		name := rest[2:]
+		nameEnd := 0
+		for nameEnd < len(name) && isTagNameChar(name[nameEnd]) {
+			nameEnd++
+		}
+		l.emit(token{typ: tokenEndTag, val: name[:nameEnd]}, l.pos+close+1)
+		return
+	}
+
+	if len(rest) > 1 && isLetter(rest[1]) {
+		if t, end, ok := lexStartTag(l.src, l.pos); ok {
+			l.emit(t, end)
+			if rawTextElements[strings.ToLower(t.val)] && !t.selfClosing && !hasAttribute(t.attrs, processAttribute) {
+				l.skipRawText(t.val)
+			}
+			return
+		}
+	}
+
+	l.pos++
+}
+
+// Emits the content of a raw text element as is,
+// up to its end tag or the end of the source
+//
+// Receiver:
+// - l (*lexer)
+//
+// Params:
+// - name (string): name of the element
+//
+// Since: 0.2.0
+func (l *lexer) skipRawText(name string) {
+	end := len(l.src)
+	for i := l.pos; i+2+len(name) <= len(l.src); i++ {
+		if l.src[i] == '<' && l.src[i+1] == '/' && strings.EqualFold(l.src[i+2:i+2+len(name)], name) {
+			end = i
+			break
+		}
+	}
+
+	if end > l.pos {
+		l.emit(token{typ: tokenRawText, val: l.src[l.pos:end]}, end)
+	}
+}
+
+// Lex a start tag beginning at pos
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the <
+//
+// Returns:
+// - token: the start tag token
+// - int: offset just past the tag
+// - bool: whether a valid tag was found
+//
+// Since: 0.2.0
+func lexStartTag(src string, pos int) (token, int, bool) {
+	i := pos + 1
+	for i < len(src) && isTagNameChar(src[i]) {
+		i++
+	}
+	t := token{typ: tokenStartTag, val: src[pos+1 : i]}
+
+	for {
+		spaceStart := i
+		i = skipSpace(src, i)
+		space := src[spaceStart:i]
+
+		switch {
+		case i >= len(src):
+			return t, 0, false
+		case src[i] == '>':
+			t.space = space
+			return t, i + 1, true
+		case strings.HasPrefix(src[i:], "/>"):
+			t.space = space
+			t.selfClosing = true
+			return t, i + 2, true
+		}
+
+		attr := &Attribute{Pos: Pos(i), Space: space}
+
+		switch {
+		case strings.HasPrefix(src[i:], "{{"):
+			end := findExpressionEnd(src, i)
+			if end < 0 {
+				return t, 0, false
+			}
+			attr.Kind = AttributeExpression
+			attr.Value = src[i+2 : end-2]
+			i = end
+		case strings.HasPrefix(src[i:], "@attributes("):
+			open := i + len("@attributes")
+			end := findParenEnd(src, open)
+			if end < 0 {
+				return t, 0, false
+			}
+			attr.Kind = AttributeDirective
+			attr.Name = "attributes"
+			attr.Value = src[open+1 : end-1]
+			i = end
+		default:
+			nameStart := i
+			for i < len(src) && !isSpace(src[i]) && !strings.ContainsRune(`="'>`, rune(src[i])) && !strings.HasPrefix(src[i:], "/>") {
+				i++
+			}
+			if i == nameStart {
+				return t, 0, false
+			}
+			attr.Name = src[nameStart:i]
+
+			eqStart := i
+			j := skipSpace(src, i)
+			if j < len(src) && src[j] == '=' {
+				j = skipSpace(src, j+1)
+				attr.Eq = src[eqStart:j]
+				attr.HasValue = true
+
+				end, ok := lexAttributeValue(src, j, attr)
+				if !ok {
+					return t, 0, false
+				}
+				i = end
+			}
+
+			if len(attr.Name) > 1 && attr.Name[0] == ':' {
+				attr.Kind = AttributeBinding
+				attr.Name = attr.Name[1:]
+			}
+		}
+
+		t.attrs = append(t.attrs, attr)
+	}
+}
+
+// Lex a quoted or unquoted attribute value.
+// Interpolations inside the value are skipped over
+// so they may contain quotes and >
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the value
+// - attr (*Attribute): attribute to fill in
+//
+// Returns:
+// - int: offset just past the value
+// - bool: whether the value is terminated
+//
+// Since: 0.2.0
+func lexAttributeValue(src string, pos int, attr *Attribute) (int, bool) {
+	i := pos
+	quote := byte(0)
+	if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+		quote = src[i]
+		i++
+	}
+	start := i
+
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "{{"):
+			end := findExpressionEnd(src, i)
+			if end < 0 {
+				return 0, false
+			}
+			i = end
+			continue
+		case quote != 0 && c == quote:
+			attr.Quote = string(quote)
+			attr.Value = src[start:i]
+			return i + 1, true
+		case quote == 0 && (isSpace(c) || c == '>'):
+			attr.Value = src[start:i]
+			return i, true
+		}
+		i++
+	}
+
+	return 0, false
+}
+
+// Finds the end of a {{ }} interpolation, skipping
+// over quoted strings inside of it
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the opening braces
+//
+// Returns:
+// - int: offset just past the closing braces, -1 if missing
+//
+// Since: 0.2.0
+func findExpressionEnd(src string, pos int) int {
+	for i := pos + 2; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'', '`':
+			i = skipQuoted(src, i)
+			if i < 0 {
+				return -1
+			}
+		case '}':
+			if strings.HasPrefix(src[i:], "}}") {
+				return i + 2
+			}
+		}
+	}
+
+	return -1
+}
+
+// Finds the closing parenthesis matching the one
+// at pos, skipping over quoted strings
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the opening parenthesis
+//
+// Returns:
+// - int: offset just past the closing parenthesis, -1 if missing
+//
+// Since: 0.2.0
+func findParenEnd(src string, pos int) int {
+	depth := 0
+	for i := pos; i < len(src); i++ {
+		switch src[i] {
+		case '"', '\'', '`':
+			i = skipQuoted(src, i)
+			if i < 0 {
+				return -1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// Skips a quoted string starting at pos
+//
+// Params:
+// - src (string): the source
+// - pos (int): offset of the opening quote
+//
+// Returns:
+// - int: offset of the closing quote, -1 if missing
+//
+// Since: 0.2.0
+func skipQuoted(src string, pos int) int {
+	quote := src[pos]
+	for i := pos + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Skips whitespace starting at pos
+//
+// Params:
+// - src (string): the source
+// - pos (int): starting offset
+//
+// Returns:
+// - int: offset of the next non space character
+//
+// Since: 0.2.0
+func skipSpace(src string, pos int) int {
+	for pos < len(src) && isSpace(src[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// Reports whether a byte is html whitespace
+//
+// Params:
+// - c (byte): the byte
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// Reports whether a byte is an ascii letter
+//
+// Params:
+// - c (byte): the byte
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Reports whether a byte can be part of a word,
+// a letter, digit or underscore
+//
+// Params:
+// - c (byte): the byte
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isWordChar(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '_'
+}
+
+// Reports whether a byte can be part of a tag name,
+// which also allows - : and . as in ui-forms.input
+//
+// Params:
+// - c (byte): the byte
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isTagNameChar(c byte) bool {
+	return isWordChar(c) || c == '-' || c == ':' || c == '.'
+}
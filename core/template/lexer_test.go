@@ -0,0 +1,135 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexAttributeValueWithSpecialCharacters(t *testing.T) {
+	example := `<ui-link href="/a/b?c>d" title="{{ "x>y" }}" />`
+
+	tokens, err := lex(&Document{Source: example})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if len(tokens) != 1 || !tokens[0].selfClosing {
+		t.Fatalf("Expected a single self closing tag, but got %v", tokens)
+	}
+
+	expected := []string{`/a/b?c>d`, `{{ "x>y" }}`}
+	var result []string
+	for _, attr := range tokens[0].attrs {
+		result = append(result, attr.Value)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+}
+
+func TestLexDirectiveArguments(t *testing.T) {
+	example := "@if LoggedIn\n<p>Hi</p>\n@end"
+
+	tokens, err := lex(&Document{Source: example})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := []tokenType{tokenDirective, tokenText, tokenStartTag, tokenText, tokenEndTag, tokenText, tokenDirective}
+	var result []tokenType
+	for _, token := range tokens {
+		result = append(result, token.typ)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+	if tokens[0].args != "LoggedIn" {
+		t.Errorf("Expected LoggedIn, but got %s", tokens[0].args)
+	}
+}
+
+func TestLexBareArgumentsEnd(t *testing.T) {
+	examples := map[string]string{
+		"@if A <b>x</b>":           "A",
+		"@if A {{ x }}":            "A",
+		"@if A @else":              "A",
+		"@if Count < 3 and B\nx":   "Count < 3 and B",
+		`@if Name == "<b> @end" x`: `Name == "<b> @end" x`,
+		"@for item in Items<li>":   "item in Items",
+		"@if A @endpoint <i>":      "A @endpoint",
+	}
+
+	for example, expected := range examples {
+		tokens, err := lex(&Document{Source: example})
+		if err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if tokens[0].args != expected {
+			t.Errorf("Expected %q for %q, but got %q", expected, example, tokens[0].args)
+		}
+	}
+}
+
+func TestLexIgnoresAtSignsInText(t *testing.T) {
+	example := `mail admin@end.com about the @endpoint`
+
+	tokens, err := lex(&Document{Source: example})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if len(tokens) != 1 || tokens[0].typ != tokenText {
+		t.Errorf("Expected a single text token, but got %v", tokens)
+	}
+}
+
+func TestLexUnclosedExpression(t *testing.T) {
+	_, err := lex(&Document{Name: "page.lamb.html", Source: "<p>\n{{ title</p>"})
+	if err == nil {
+		t.Fatalf("Expected an error, but got none")
+	}
+
+	expected := "page.lamb.html:2:1: unclosed {{"
+	if err.Error() != expected {
+		t.Errorf("Expected error message '%s', but got '%s'", expected, err.Error())
+	}
+}
+
+func TestLexAttributeBinding(t *testing.T) {
+	example := `<a :href="User.ProfileURL" class="link">`
+
+	tokens, err := lex(&Document{Source: example})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	attr := tokens[0].attrs[0]
+	if attr.Kind != AttributeBinding || attr.Name != "href" || attr.Value != "User.ProfileURL" {
+		t.Errorf("Expected binding href=User.ProfileURL, but got %#v", attr)
+	}
+	if tokens[0].attrs[1].Kind != AttributeNormal {
+		t.Errorf("Expected a normal attribute, but got %#v", tokens[0].attrs[1])
+	}
+}
+
+func TestLexSkipsCommentsAndRawText(t *testing.T) {
+	example := "<!-- {{ not closed <ui-a> --><script>x = '</p>' {{</script>"
+
+	tokens, err := lex(&Document{Source: example})
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := []tokenType{tokenRawText, tokenStartTag, tokenRawText, tokenEndTag}
+	var result []tokenType
+	for _, token := range tokens {
+		result = append(result, token.typ)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+}
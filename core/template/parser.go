@@ -1,93 +1,944 @@
 package template
 
 import (
+	"fmt"
+	"path/filepath"
 	"strings"
 )
 
-// Parse the lamb file
+// Parse the lamb file. Layouts are resolved
+// from the directory of the file
 //
 // Params:
-// - filepath (string): Path to the lamb file
+// - filePath (string): Path to the lamb file
 //
 // Returns:
 // - string: The parsed content
 // - error: if something goes wrong
 //
 // Since: 0.1.0
-func ParseLamb(filepath string, componentDir string) (string, error) {
-	content, err := getContent(filepath)
+func ParseLamb(filePath string, componentDir string) (string, error) {
+	dir := filepath.Dir(filePath)
+	return ParsePage(filePath, Options{ComponentDir: componentDir, LayoutDir: dir, PartialDir: dir})
+}
+
+// Compile a page to go template source in memory
+//
+// Params:
+// - filePath (string): path to the page, relative to
+// options.PageFS when one is set
+// - options (Options): where components and layouts are read from
+//
+// Returns:
+// - string: the go template source
+// - error: if something goes wrong
+//
+// Since: 0.2.0
+func ParsePage(filePath string, options Options) (string, error) {
+	g := newGenerator(options)
+
+	doc, err := g.loadPage(filePath)
 	if err != nil {
 		return "", err
 	}
 
-	content = replaceSyntax(content)
-	closingComponents := getSelfClosingUIComponents(content, componentDir)
-	for _, closingComponent := range closingComponents {
-		content, err = replaceSelfClosingComponents(&closingComponent, content, componentDir)
+	return g.generate(doc)
+}
+
+// Parse lamb source into a syntax tree
+//
+// Params:
+// - name (string): name of the source, used in errors
+// - src (string): the lamb source
+//
+// Returns:
+// - *Document: the parsed document
+// - error: if the source is malformed
+//
+// Since: 0.2.0
+func Parse(name string, src string) (*Document, error) {
+	return parse(name, src, []string{componentPrefix})
+}
+
+// Parse lamb source into a syntax tree, recognizing
+// components by the given tag prefixes
+//
+// Params:
+// - name (string): name of the source, used in errors
+// - src (string): the lamb source
+// - prefixes ([]string): tag prefixes of components,
+// defaults to ui- when empty
+// ex: ui-, acme-
+//
+// Returns:
+// - *Document: the parsed document
+// - error: if the source is malformed
+//
+// Since: 0.2.0
+func ParseWithPrefixes(name string, src string, prefixes []string) (*Document, error) {
+	if len(prefixes) == 0 {
+		prefixes = []string{componentPrefix}
+	}
+
+	return parse(name, src, prefixes)
+}
+
+// Parse lamb source into a document, recognizing
+// components by the given tag prefixes
+//
+// Params:
+// - name (string): file name used in errors
+// - src (string): the source
+// - prefixes ([]string): tag prefixes of components
+// ex: ui-, acme-
+//
+// Returns:
+// - *Document: the parsed document
+// - error: if the source is malformed
+//
+// Since: 0.2.0
+func parse(name string, src string, prefixes []string) (*Document, error) {
+	doc := &Document{Name: name, Source: src}
+
+	tokens, err := lex(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{doc: doc, tokens: tokens, prefixes: prefixes}
+	doc.Nodes, err = p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.i < len(p.tokens) {
+		t := p.tokens[p.i]
+		return nil, doc.errorf(t.pos, "unexpected %s", p.describe(t))
+	}
+
+	if doc.Extends != nil {
+		for _, node := range doc.Nodes {
+			switch n := node.(type) {
+			case *ExtendsNode, *SectionNode:
+			case *TextNode:
+				if strings.TrimSpace(n.Text) != "" {
+					return nil, doc.errorf(n.Pos, "content outside of @section in a file extending a layout")
+				}
+			default:
+				return nil, doc.errorf(n.Position(), "content outside of @section in a file extending a layout")
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// A construct whose content is being parsed
+//
+// Fields:
+// - name (string): tag or directive name
+// - directive (bool): construct is a directive block
+// - component (bool): construct is a component
+// - pos (Pos): position of the start tag of an element
+//
+// Since: 0.2.0
+type openNode struct {
+	name      string
+	directive bool
+	component bool
+	pos       Pos
+}
+
+// Builds the syntax tree from tokens
+//
+// Fields:
+// - doc (*Document): document being parsed
+// - tokens ([]token): lexed tokens
+// - i (int): index of the current token
+// - open ([]openNode): constructs being parsed, innermost last
+// - prefixes ([]string): tag prefixes of components
+// ex: ui-
+//
+// Since: 0.2.0
+type parser struct {
+	doc      *Document
+	tokens   []token
+	i        int
+	open     []openNode
+	prefixes []string
+}
+
+// Parse nodes until the end of the input or a token
+// that closes one of the open constructs
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - []Node: the parsed nodes
+// - error: if the tokens are malformed
+//
+// Since: 0.2.0
+func (p *parser) parseNodes() ([]Node, error) {
+	var nodes []Node
+
+	for p.i < len(p.tokens) {
+		t := p.tokens[p.i]
+
+		switch t.typ {
+		case tokenText:
+			nodes = append(nodes, &TextNode{Pos: t.pos, Text: t.val})
+			p.i++
+		case tokenExpression:
+			nodes = append(nodes, &ExpressionNode{Pos: t.pos, Expr: t.val, Raw: p.source(t)})
+			p.i++
+		case tokenVerbatim:
+			nodes = append(nodes, &VerbatimNode{Pos: t.pos, Text: t.val, Raw: p.source(t)})
+			p.i++
+		case tokenRawText:
+			nodes = append(nodes, &TextNode{Pos: t.pos, Text: t.val, RawText: true})
+			p.i++
+		case tokenDirective:
+			var node Node
+			var err error
+
+			switch t.val {
+			case "if":
+				node, err = p.parseIf()
+			case "for":
+				node, err = p.parseFor()
+			case "switch":
+				node, err = p.parseSwitch()
+			case "with":
+				node, err = p.parseWith()
+			case "let":
+				node, err = p.parseLet()
+			case "extends":
+				node, err = p.parseExtends()
+			case "section":
+				node, err = p.parseSection()
+			case "yield":
+				node, err = p.parseYield()
+			case "include":
+				node, err = p.parseInclude()
+			case "props":
+				node, err = p.parseProps()
+			default:
+				if p.closesDirective() {
+					return nodes, nil
+				}
+				return nil, p.doc.errorf(t.pos, "unexpected %s", p.describe(t))
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		case tokenStartTag:
+			node, err := p.parseElement()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+		case tokenEndTag:
+			if p.closesElement(t.val) {
+				return nodes, nil
+			}
+			if _, ok := p.componentName(t.val); ok {
+				return nil, p.doc.errorf(t.pos, "%s", p.unmatched(t))
+			}
+
+			// Stray html end tags are kept as they are
+			nodes = append(nodes, &TextNode{Pos: t.pos, Text: p.source(t)})
+			p.i++
+		}
+	}
+
+	return nodes, nil
+}
+
+// Parse an element or component starting
+// at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: *ElementNode or *ComponentNode
+// - error: if a component is never closed
+//
+// Since: 0.2.0
+func (p *parser) parseElement() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	el := ElementNode{
+		Pos:         t.pos,
+		Name:        t.val,
+		Attrs:       t.attrs,
+		Space:       t.space,
+		SelfClosing: t.selfClosing,
+		TagEnd:      t.end,
+		ClosePos:    t.end,
+		End:         t.end,
+	}
+	_, component := p.componentName(t.val)
+
+	for _, attr := range t.attrs {
+		if attr.Kind == AttributeBinding && strings.TrimSpace(attr.Value) == "" {
+			return nil, p.doc.errorf(attr.Pos, "binding :%s requires an expression", attr.Name)
+		}
+		if attr.Kind != AttributeDirective {
+			continue
+		}
+		if _, err := parseAttributesDirective(attr.Value); err != nil {
+			return nil, p.doc.errorf(attr.Pos, "malformed @attributes: %s", err.Error())
+		}
+	}
+
+	if t.selfClosing || (!component && voidElements[strings.ToLower(t.val)]) {
+		return p.wrap(el, component), nil
+	}
+
+	p.open = append(p.open, openNode{name: t.val, component: component, pos: t.pos})
+	children, err := p.parseNodes()
+	p.open = p.open[:len(p.open)-1]
+	if err != nil {
+		return nil, err
+	}
+	el.Children = children
+
+	if p.i < len(p.tokens) && p.tokens[p.i].typ == tokenEndTag && strings.EqualFold(p.tokens[p.i].val, t.val) {
+		end := p.tokens[p.i]
+		el.Closed = true
+		el.ClosePos = end.pos
+		el.End = end.end
+		p.i++
+		return p.wrap(el, component), nil
+	}
+
+	if component {
+		return nil, p.doc.errorf(t.pos, "unclosed <%s>", t.val)
+	}
+
+	// Html elements without an end tag are closed implicitly
+	el.ClosePos = p.offset()
+	el.End = el.ClosePos
+	return p.wrap(el, component), nil
+}
+
+// Wraps an element in a component node when needed
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - el (ElementNode): the element
+// - component (bool): element is a component
+//
+// Returns:
+// - Node: the node
+//
+// Since: 0.2.0
+func (p *parser) wrap(el ElementNode, component bool) Node {
+	if name, ok := p.componentName(el.Name); component && ok {
+		return &ComponentNode{ElementNode: el, Component: name}
+	}
+	return &el
+}
+
+// Finds the component name of a tag by
+// the first prefix it starts with
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - tag (string): tag name
+// ex: acme-button
+//
+// Returns:
+// - string: the name without the prefix
+// ex: button
+// - bool: whether the tag is a component
+//
+// Since: 0.2.0
+func (p *parser) componentName(tag string) (string, bool) {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(tag, prefix) && len(tag) > len(prefix) {
+			return tag[len(prefix):], true
+		}
+	}
+
+	return "", false
+}
+
+// Parse an @if block starting at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *IfNode
+// - error: if the block is malformed or never closed
+//
+// Since: 0.2.0
+func (p *parser) parseIf() (Node, error) {
+	start := p.tokens[p.i]
+	p.i++
+
+	if start.args == "" {
+		return nil, p.doc.errorf(start.pos, "@if requires a condition")
+	}
+
+	node := &IfNode{Pos: start.pos}
+	branch := &Branch{Pos: start.pos, Cond: start.args, Parens: start.parens}
+	node.Branches = append(node.Branches, branch)
+
+	p.open = append(p.open, openNode{name: "if", directive: true})
+	defer func() { p.open = p.open[:len(p.open)-1] }()
+
+	for {
+		body, err := p.parseNodes()
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		branch.Body = body
+
+		if p.i >= len(p.tokens) {
+			return nil, p.doc.errorf(start.pos, "unclosed @if, missing @end")
+		}
+
+		t := p.tokens[p.i]
+		p.i++
+
+		switch t.val {
+		case "elseif":
+			if node.Else != nil {
+				return nil, p.doc.errorf(t.pos, "@elseif after @else")
+			}
+			if t.args == "" {
+				return nil, p.doc.errorf(t.pos, "@elseif requires a condition")
+			}
+			branch = &Branch{Pos: t.pos, Cond: t.args, Parens: t.parens}
+			node.Branches = append(node.Branches, branch)
+		case "else":
+			if node.Else != nil {
+				return nil, p.doc.errorf(t.pos, "duplicate @else")
+			}
+			branch = &Branch{Pos: t.pos}
+			node.Else = branch
+		case "end":
+			return node, nil
+		default:
+			return nil, p.doc.errorf(t.pos, "unexpected %s", p.describe(t))
 		}
 	}
-	wrappedComponents := getWrappedUIComponents(content, componentDir)
-	for _, wrappedComponent := range wrappedComponents {
-		content, err = replaceWrappedComponents(&wrappedComponent, content, componentDir)
+}
+
+// Parse an @for block starting at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *ForNode
+// - error: if the block is malformed or never closed
+//
+// Since: 0.2.0
+func (p *parser) parseFor() (Node, error) {
+	start := p.tokens[p.i]
+	p.i++
+
+	key, value, collection, ok := splitForArgs(start.args)
+	if !ok {
+		return nil, p.doc.errorf(start.pos, "malformed @for, expected @for item in items")
+	}
+	if key == value {
+		return nil, p.doc.errorf(start.pos, "@for binds %s twice", key)
+	}
+	node := &ForNode{Pos: start.pos, Key: key, Value: value, Collection: collection, Parens: start.parens}
+
+	p.open = append(p.open, openNode{name: "for", directive: true})
+	defer func() { p.open = p.open[:len(p.open)-1] }()
+
+	body := &node.Body
+	for {
+		nodes, err := p.parseNodes()
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		*body = nodes
+
+		if p.i >= len(p.tokens) {
+			return nil, p.doc.errorf(start.pos, "unclosed @for, missing @end")
+		}
+
+		t := p.tokens[p.i]
+		p.i++
+
+		switch {
+		case t.val == "end":
+			return node, nil
+		case t.val == "empty" && node.Empty == nil:
+			node.Empty = &Branch{Pos: t.pos}
+			body = &node.Empty.Body
+		case t.val == "empty":
+			return nil, p.doc.errorf(t.pos, "duplicate @empty")
+		default:
+			return nil, p.doc.errorf(t.pos, "unexpected %s inside @for", p.describe(t))
 		}
 	}
+}
+
+// Parse an @switch block starting at the current token.
+// Only whitespace may appear before the first @case
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *SwitchNode
+// - error: if the block is malformed or never closed
+//
+// Since: 0.2.0
+func (p *parser) parseSwitch() (Node, error) {
+	start := p.tokens[p.i]
+	p.i++
+
+	if start.args == "" {
+		return nil, p.doc.errorf(start.pos, "@switch requires a value")
+	}
+	node := &SwitchNode{Pos: start.pos, Value: start.args, Parens: start.parens}
+
+	p.open = append(p.open, openNode{name: "switch", directive: true})
+	defer func() { p.open = p.open[:len(p.open)-1] }()
 
-	return content, nil
+	lead, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range lead {
+		text, ok := n.(*TextNode)
+		if !ok || strings.TrimSpace(text.Text) != "" {
+			return nil, p.doc.errorf(n.Position(), "unexpected content before the first @case")
+		}
+		node.Lead += text.Text
+	}
+
+	var branch *Branch
+	for {
+		if p.i >= len(p.tokens) {
+			return nil, p.doc.errorf(start.pos, "unclosed @switch, missing @end")
+		}
+
+		t := p.tokens[p.i]
+		p.i++
+
+		switch t.val {
+		case "case":
+			if node.Default != nil {
+				return nil, p.doc.errorf(t.pos, "@case after @default")
+			}
+			if t.args == "" {
+				return nil, p.doc.errorf(t.pos, "@case requires a value")
+			}
+			branch = &Branch{Pos: t.pos, Cond: t.args, Parens: t.parens}
+			node.Cases = append(node.Cases, branch)
+		case "default":
+			if node.Default != nil {
+				return nil, p.doc.errorf(t.pos, "duplicate @default")
+			}
+			branch = &Branch{Pos: t.pos}
+			node.Default = branch
+		case "end":
+			if len(node.Cases) == 0 {
+				return nil, p.doc.errorf(start.pos, "@switch requires at least one @case")
+			}
+			return node, nil
+		default:
+			return nil, p.doc.errorf(t.pos, "unexpected %s inside @switch", p.describe(t))
+		}
+
+		body, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		branch.Body = body
+	}
 }
 
-// Replace self closing component syntax
-// with the component file
+// Parse an @with block starting at the current token
 //
-// Params:
-// - component (*SelfClosingUIComponent): self closing component struct
-// - content (string): content to parse
+// Receiver:
+// - p (*parser)
 //
 // Returns:
-// - string: parsed content
-// - error: if something goes wrong
+// - Node: the *WithNode
+// - error: if the block is malformed or never closed
 //
-// Since: 0.1.0
-func replaceSelfClosingComponents(component *SelfClosingUIComponent, content string, componentDir string) (string, error) {
-	componentContent, err := ParseLamb(component.ComponentFilePath, componentDir)
+// Since: 0.2.0
+func (p *parser) parseWith() (Node, error) {
+	start := p.tokens[p.i]
+	p.i++
+
+	if start.args == "" {
+		return nil, p.doc.errorf(start.pos, "@with requires a value")
+	}
+	node := &WithNode{Pos: start.pos, Value: start.args, Parens: start.parens}
+
+	p.open = append(p.open, openNode{name: "with", directive: true})
+	defer func() { p.open = p.open[:len(p.open)-1] }()
+
+	body := &node.Body
+	for {
+		nodes, err := p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+		*body = nodes
+
+		if p.i >= len(p.tokens) {
+			return nil, p.doc.errorf(start.pos, "unclosed @with, missing @end")
+		}
+
+		t := p.tokens[p.i]
+		p.i++
+
+		switch {
+		case t.val == "end":
+			return node, nil
+		case t.val == "else" && node.Else == nil:
+			node.Else = &Branch{Pos: t.pos}
+			body = &node.Else.Body
+		case t.val == "else":
+			return nil, p.doc.errorf(t.pos, "duplicate @else")
+		default:
+			return nil, p.doc.errorf(t.pos, "unexpected %s inside @with", p.describe(t))
+		}
+	}
+}
+
+// Parse an @let declaration starting at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *LetNode
+// - error: if the declaration is malformed
+//
+// Since: 0.2.0
+func (p *parser) parseLet() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	name, value, ok := splitLetArgs(t.args)
+	if !ok {
+		return nil, p.doc.errorf(t.pos, "malformed @let, expected @let name = value")
+	}
+
+	return &LetNode{Pos: t.pos, Name: name, Value: value, Parens: t.parens}, nil
+}
+
+// Parse an @extends declaration starting at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *ExtendsNode
+// - error: if the declaration is malformed or misplaced
+//
+// Since: 0.2.0
+func (p *parser) parseExtends() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	if len(p.open) > 0 {
+		return nil, p.doc.errorf(t.pos, "@extends must be declared at the top level")
+	}
+	if p.doc.Extends != nil {
+		return nil, p.doc.errorf(t.pos, "duplicate @extends")
+	}
+
+	args, err := parseStringArguments(t.args, 1, 1)
 	if err != nil {
-		return "", err
+		return nil, p.doc.errorf(t.pos, "malformed @extends: %s", err.Error())
 	}
 
-	componentContent = applyAttributesDirective(componentContent, *component.Attributes)
+	node := &ExtendsNode{Pos: t.pos, Layout: args[0]}
+	p.doc.Extends = node
+	return node, nil
+}
 
-	updatedContent := strings.Replace(content, component.Element, componentContent, 1)
-	return updatedContent, nil
+// Parse an @section block starting at the current token.
+// Sections appear at the top level of files extending
+// a layout
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *SectionNode
+// - error: if the block is malformed, misplaced or never closed
+//
+// Since: 0.2.0
+func (p *parser) parseSection() (Node, error) {
+	start := p.tokens[p.i]
+	p.i++
+
+	if len(p.open) > 0 || p.doc.Extends == nil {
+		return nil, p.doc.errorf(start.pos, "@section must be at the top level, after @extends")
+	}
+
+	args, err := parseStringArguments(start.args, 1, 1)
+	if err != nil {
+		return nil, p.doc.errorf(start.pos, "malformed @section: %s", err.Error())
+	}
+	node := &SectionNode{Pos: start.pos, Name: args[0]}
+
+	p.open = append(p.open, openNode{name: "section", directive: true})
+	defer func() { p.open = p.open[:len(p.open)-1] }()
+
+	body, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	node.Body = body
+
+	if p.i >= len(p.tokens) {
+		return nil, p.doc.errorf(start.pos, "unclosed @section, missing @end")
+	}
+
+	t := p.tokens[p.i]
+	p.i++
+	if t.val != "end" {
+		return nil, p.doc.errorf(t.pos, "unexpected %s inside @section", p.describe(t))
+	}
+
+	return node, nil
 }
 
-// Replace wrapped component syntax
-// with the component file
+// Parse an @yield placeholder starting at the current token
 //
-// Params:
-// - component (*WrappedUIComponent): wrapped component struct
-// - content (string): content to parse
+// Receiver:
+// - p (*parser)
 //
 // Returns:
-// - string: parsed content
-// - error: if something goes wrong
+// - Node: the *YieldNode
+// - error: if the arguments are malformed
 //
-// Since: 0.1.0
-func replaceWrappedComponents(component *WrappedUIComponent, content string, componentDir string) (string, error) {
-	componentContent, err := ParseLamb(component.ComponentFilePath, componentDir)
+// Since: 0.2.0
+func (p *parser) parseYield() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	args, err := parseStringArguments(t.args, 1, 2)
 	if err != nil {
-		return "", err
+		return nil, p.doc.errorf(t.pos, "malformed @yield: %s", err.Error())
 	}
 
-	componentContent = applyAttributesDirective(componentContent, *component.Attributes)
+	node := &YieldNode{Pos: t.pos, Name: args[0]}
+	if len(args) > 1 {
+		node.Default = args[1]
+	}
 
-	// Replace the <slot /> placeholder with the wrapped content
-	updatedContent := strings.Replace(componentContent, "<slot />", component.InnerContent, 1)
+	return node, nil
+}
 
-	// Replace the entire wrapped component block
-	updatedContent = strings.Replace(content, component.Element, updatedContent, 1)
+// Parse an @include starting at the current token
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *IncludeNode
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func (p *parser) parseInclude() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	name, data, err := parseIncludeArguments(t.args)
+	if err != nil {
+		return nil, p.doc.errorf(t.pos, "malformed @include: %s", err.Error())
+	}
+
+	return &IncludeNode{Pos: t.pos, Name: name, Data: data}, nil
+}
+
+// Parse an @props declaration starting at the current
+// token. It must appear once, outside of any block
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Node: the *PropsNode
+// - error: if the declaration is malformed or misplaced
+//
+// Since: 0.2.0
+func (p *parser) parseProps() (Node, error) {
+	t := p.tokens[p.i]
+	p.i++
+
+	if len(p.open) > 0 {
+		return nil, p.doc.errorf(t.pos, "@props must be declared at the top level")
+	}
+	if p.doc.Props != nil {
+		return nil, p.doc.errorf(t.pos, "duplicate @props")
+	}
+	if t.args == "" {
+		return nil, p.doc.errorf(t.pos, "@props requires a list of props")
+	}
+
+	props, err := parsePropsDirective(t.args)
+	if err != nil {
+		return nil, p.doc.errorf(t.pos, "malformed @props: %s", err.Error())
+	}
+
+	node := &PropsNode{Pos: t.pos, Args: t.args, Props: props}
+	for _, prop := range props {
+		prop.Pos = t.pos
+	}
+	p.doc.Props = node
+
+	return node, nil
+}
+
+// Reports whether a directive branch or @end belongs
+// to an open directive. Html elements in between are
+// closed implicitly, components are not
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (p *parser) closesDirective() bool {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		switch {
+		case p.open[i].directive:
+			return true
+		case p.open[i].component:
+			return false
+		}
+	}
+	return false
+}
+
+// Reports whether an end tag closes an open element
+// or component without crossing a directive or component
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - name (string): name of the end tag
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (p *parser) closesElement(name string) bool {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		open := p.open[i]
+		switch {
+		case open.directive:
+			return false
+		case strings.EqualFold(open.name, name):
+			return true
+		case open.component:
+			return false
+		}
+	}
+	return false
+}
 
-	return updatedContent, nil
+// Describes a component end tag that closes nothing,
+// with what the innermost open directive or component
+// expects instead
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - t (token): the end tag
+//
+// Returns:
+// - string
+// ex: mismatched </ui-button>, expected </ui-card> to close <ui-card> from 1:1
+//
+// Since: 0.2.0
+func (p *parser) unmatched(t token) string {
+	for i := len(p.open) - 1; i >= 0; i-- {
+		open := p.open[i]
+		switch {
+		case open.directive:
+			return fmt.Sprintf("unmatched %s, @%s is not closed", p.describe(t), open.name)
+		case open.component:
+			line, column := p.doc.LineColumn(open.pos)
+			return fmt.Sprintf("mismatched %s, expected </%s> to close <%s> from %d:%d", p.describe(t), open.name, open.name, line, column)
+		}
+	}
+	return "unmatched " + p.describe(t)
+}
+
+// Offset of the current token, or the end of
+// the source when all tokens are consumed
+//
+// Receiver:
+// - p (*parser)
+//
+// Returns:
+// - Pos
+//
+// Since: 0.2.0
+func (p *parser) offset() Pos {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i].pos
+	}
+	return Pos(len(p.doc.Source))
+}
+
+// Source text of a token
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - t (token): the token
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (p *parser) source(t token) string {
+	return p.doc.Source[t.pos:t.end]
+}
+
+// Describes a token for error messages
+//
+// Receiver:
+// - p (*parser)
+//
+// Params:
+// - t (token): the token
+//
+// Returns:
+// - string
+//
+// Since: 0.2.0
+func (p *parser) describe(t token) string {
+	switch t.typ {
+	case tokenDirective:
+		return "@" + t.val
+	case tokenEndTag:
+		return "</" + t.val + ">"
+	}
+	return fmt.Sprintf("%q", p.source(t))
 }
@@ -0,0 +1,117 @@
+package template
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNestedSameNameComponents(t *testing.T) {
+	example := `<ui-card class="outer"><ui-card>inner</ui-card></ui-card>`
+
+	doc, err := Parse("", example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if len(doc.Nodes) != 1 {
+		t.Fatalf("Expected a single node, but got %d", len(doc.Nodes))
+	}
+
+	outer, ok := doc.Nodes[0].(*ComponentNode)
+	if !ok || len(outer.Children) != 1 {
+		t.Fatalf("Expected a component with one child, but got %#v", doc.Nodes[0])
+	}
+
+	inner, ok := outer.Children[0].(*ComponentNode)
+	if !ok || inner.Component != "card" {
+		t.Errorf("Expected a nested card component, but got %#v", outer.Children[0])
+	}
+}
+
+func TestParseDirectiveInsideElements(t *testing.T) {
+	example := "@if Active\n<div class=\"a\">\n@else\n<div class=\"b\">\n@end\n</div>"
+
+	doc, err := Parse("", example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if _, ok := doc.Nodes[0].(*IfNode); !ok {
+		t.Errorf("Expected an if node, but got %#v", doc.Nodes[0])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	examples := map[string]string{
+		"@if LoggedIn\n<p>Hi</p>":            "page:1:1: unclosed @if, missing @end",
+		"<p>Hi</p>\n@end":                    "page:2:1: unexpected @end",
+		"<ui-card>\n</ui-button>":            "page:2:1: mismatched </ui-button>, expected </ui-card> to close <ui-card> from 1:1",
+		"<ui-card>\n@if A\n</ui-card>":       "page:3:1: unmatched </ui-card>, @if is not closed",
+		"<ui-card>\n<p>Hi</p>":               "page:1:1: unclosed <ui-card>",
+		"@for user\n@end":                    "page:1:1: malformed @for, expected @for item in items",
+		"@for a in B\n@empty\n@empty\n@end":  "page:3:1: duplicate @empty",
+		"<ui-card><ui-container>x</ui-card>": "page:1:25: mismatched </ui-card>, expected </ui-container> to close <ui-container> from 1:10",
+		"<ui-card>\n  <ui-card>\n    <ui-card>x</ui-card>\n  </ui-cards>\n</ui-card>": "page:4:3: mismatched </ui-cards>, expected </ui-card> to close <ui-card> from 2:3",
+		"<ui-card><ui-card>inner</ui-card>":                                           "page:1:1: unclosed <ui-card>",
+		"x</ui-card>":                                                                 "page:1:2: unmatched </ui-card>",
+		"x\n@include(nav)":                                                            "page:2:1: malformed @include: expected a quoted string, got nav",
+		"x\n@extends(\"a\")":                                                          "page:1:1: content outside of @section in a file extending a layout",
+		"@extends(\"a\")\n@extends(\"b\")":                                            "page:2:1: duplicate @extends",
+		"@extends(a)":                                                                 "page:1:1: malformed @extends: expected a quoted string, got a",
+		"@section(\"a\")\nx\n@end":                                                    "page:1:1: @section must be at the top level, after @extends",
+		"@extends(\"a\")\n@section(\"b\")\nx":                                         "page:2:1: unclosed @section, missing @end",
+		"@if A\n@extends(\"a\")\n@end":                                                "page:2:1: @extends must be declared at the top level",
+		"<p>\n@verbatim {{ x }}":                                                      "page:2:1: unclosed @verbatim, missing @endverbatim",
+		"@let x\n":                                                                    "page:1:1: malformed @let, expected @let name = value",
+		"@with\n@end":                                                                 "page:1:1: @with requires a value",
+		"@with A\n<p>":                                                                "page:1:1: unclosed @with, missing @end",
+		"@switch S\n<p>x</p>\n@case 1\n@end":                                          "page:2:1: unexpected content before the first @case",
+		"@switch S\n@default\n@case 1\n@end":                                          "page:3:1: @case after @default",
+		"@switch S\n@default\n@end":                                                   "page:1:1: @switch requires at least one @case",
+		"@switch S\n@case 1\n":                                                        "page:1:1: unclosed @switch, missing @end",
+		"@if A\n@empty\n@end":                                                         "page:2:1: unexpected @empty",
+		"@if A\n@else\n@elseif B\n@end":                                               "page:3:1: @elseif after @else",
+		"<div>\n@props(a: string)</div>":                                              "page:2:1: @props must be declared at the top level",
+		"@props(a: number)":                                                           "page:1:1: malformed @props: unknown type number of prop a",
+		"@props(a: int = \"1\")":                                                      "page:1:1: malformed @props: default of prop a must be an int, got \"\\\"1\\\"\"",
+	}
+
+	for example, expected := range examples {
+		_, err := Parse("page", example)
+		if err == nil {
+			t.Errorf("Expected an error for %q, but got none", example)
+		} else if err.Error() != expected {
+			t.Errorf("Expected error message '%s', but got '%s'", expected, err.Error())
+		}
+	}
+}
+
+func TestParseLambNestedComponents(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"page.lamb.html":            `<ui-card><ui-card>inner</ui-card></ui-card>`,
+		"components/card.lamb.html": `<div @attributes("class": "card")><slot /></div>`,
+	})
+
+	result, err := ParseLamb(filepath.Join(dir, "page.lamb.html"), filepath.Join(dir, "components"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	expected := `<div class="card"><div class="card">inner</div></div>`
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, but got %v", expected, result)
+	}
+}
+
+func TestParseLambSelfIncludingComponent(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{"loop.lamb.html": `<ui-loop />`})
+
+	_, err := ParseLamb(filepath.Join(dir, "loop.lamb.html"), dir)
+	if err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("Expected a recursion error, but got %v", err)
+	}
+}
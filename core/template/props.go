@@ -0,0 +1,409 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse the arguments of an @props directive, a comma
+// separated list of name: type pairs with optional defaults
+// ex: title: string, count: int = 0, variant: "primary"|"secondary"
+//
+// Params:
+// - args (string): directive arguments
+//
+// Returns:
+// - []*Prop: the declared props
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func parsePropsDirective(args string) ([]*Prop, error) {
+	var props []*Prop
+	seen := make(map[string]bool)
+
+	for _, arg := range splitArguments(args) {
+		name, spec, ok := strings.Cut(arg, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !identifierRegex.MatchString(name) {
+			return nil, fmt.Errorf("expected name: type at %q", arg)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate prop %s", name)
+		}
+		seen[name] = true
+
+		prop := &Prop{Name: name}
+
+		typ, def, hasDefault := cutDefault(spec)
+		if err := prop.parseType(typ); err != nil {
+			return nil, err
+		}
+
+		if hasDefault {
+			value, err := prop.parseDefault(def)
+			if err != nil {
+				return nil, err
+			}
+			prop.Default = value
+			prop.HasDefault = true
+		}
+
+		props = append(props, prop)
+	}
+
+	return props, nil
+}
+
+// Splits arguments on commas outside of quotes
+// and parentheses
+//
+// Params:
+// - args (string): the arguments
+//
+// Returns:
+// - []string: trimmed arguments, empty ones are dropped
+//
+// Since: 0.2.0
+func splitArguments(args string) []string {
+	var parts []string
+	depth, start := 0, 0
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case '"', '\'', '`':
+			if end := skipQuoted(args, i); end >= 0 {
+				i = end
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, args[start:])
+
+	var trimmed []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			trimmed = append(trimmed, part)
+		}
+	}
+
+	return trimmed
+}
+
+// Parse directive arguments that must all be
+// quoted strings
+// ex: "title", "Default"
+//
+// Params:
+// - args (string): directive arguments
+// - min (int): minimum number of arguments
+// - max (int): maximum number of arguments
+//
+// Returns:
+// - []string: the unquoted arguments
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func parseStringArguments(args string, min int, max int) ([]string, error) {
+	parts := splitArguments(args)
+	if len(parts) < min || len(parts) > max {
+		if min == max {
+			return nil, fmt.Errorf("expected %d quoted string arguments, got %d", min, len(parts))
+		}
+		return nil, fmt.Errorf("expected %d to %d quoted string arguments, got %d", min, max, len(parts))
+	}
+
+	values := make([]string, len(parts))
+	for i, part := range parts {
+		value, err := strconv.Unquote(part)
+		if err != nil {
+			return nil, fmt.Errorf("expected a quoted string, got %s", part)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// Parse the arguments of an @include, a quoted partial
+// name and an optional data expression. Single quotes
+// are accepted so it can be written in attribute values
+// ex: "partials/nav", Menu
+//
+// Params:
+// - args (string): directive arguments
+//
+// Returns:
+// - string: the partial name
+// - string: the data expression, empty when omitted
+// - error: if the arguments are malformed
+//
+// Since: 0.2.0
+func parseIncludeArguments(args string) (string, string, error) {
+	parts := splitArguments(args)
+	if len(parts) < 1 || len(parts) > 2 {
+		return "", "", fmt.Errorf("expected a quoted partial name and an optional expression, got %d arguments", len(parts))
+	}
+
+	name := parts[0]
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		name = name[1 : len(name)-1]
+	} else if unquoted, err := strconv.Unquote(name); err == nil {
+		name = unquoted
+	} else {
+		return "", "", fmt.Errorf("expected a quoted string, got %s", parts[0])
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("partial name is empty")
+	}
+
+	if len(parts) == 1 {
+		return name, "", nil
+	}
+
+	return name, parts[1], nil
+}
+
+// Cuts the default value from a prop type
+// ex: int = 0
+//
+// Params:
+// - spec (string): text after the prop name
+//
+// Returns:
+// - string: the type
+// - string: the default literal
+// - bool: whether a default is given
+//
+// Since: 0.2.0
+func cutDefault(spec string) (string, string, bool) {
+	for i := 0; i < len(spec); i++ {
+		switch spec[i] {
+		case '"', '\'', '`':
+			if end := skipQuoted(spec, i); end >= 0 {
+				i = end
+			}
+		case '=':
+			return strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:]), true
+		}
+	}
+
+	return strings.TrimSpace(spec), "", false
+}
+
+// Sets the type of a prop from its declaration.
+// A union of string literals declares an enum
+//
+// Receiver:
+// - p (*Prop)
+//
+// Params:
+// - typ (string): the declared type
+// ex: "primary"|"secondary"
+//
+// Returns:
+// - error: if the type is unknown
+//
+// Since: 0.2.0
+func (p *Prop) parseType(typ string) error {
+	switch typ {
+	case "string", "int", "float", "bool":
+		p.Type = typ
+		return nil
+	case "":
+		return fmt.Errorf("prop %s has no type", p.Name)
+	}
+
+	if typ[0] != '"' && typ[0] != '`' {
+		return fmt.Errorf("unknown type %s of prop %s", typ, p.Name)
+	}
+
+	for _, option := range strings.Split(typ, "|") {
+		value, err := strconv.Unquote(strings.TrimSpace(option))
+		if err != nil {
+			return fmt.Errorf("malformed option %s of prop %s", strings.TrimSpace(option), p.Name)
+		}
+		p.Options = append(p.Options, value)
+	}
+	p.Type = "enum"
+
+	return nil
+}
+
+// Converts a default literal to the value
+// a caller would write in an attribute
+//
+// Receiver:
+// - p (*Prop)
+//
+// Params:
+// - literal (string): the default as declared
+// ex: "primary"
+//
+// Returns:
+// - string: the value
+// - error: if the literal does not match the type
+//
+// Since: 0.2.0
+func (p *Prop) parseDefault(literal string) (string, error) {
+	value := literal
+	if p.Type == "string" || p.Type == "enum" {
+		unquoted, err := strconv.Unquote(literal)
+		if err != nil {
+			return "", fmt.Errorf("default of prop %s must be a quoted string", p.Name)
+		}
+		value = unquoted
+	}
+
+	if _, err := p.operand(value); err != nil {
+		return "", fmt.Errorf("default of prop %s %s", p.Name, err.Error())
+	}
+
+	return value, nil
+}
+
+// Converts a value to a go template literal,
+// checking it against the type of the prop
+//
+// Receiver:
+// - p (*Prop)
+//
+// Params:
+// - value (string): the value
+//
+// Returns:
+// - string: the literal
+// ex: "primary"
+// - error: if the value does not match the type
+//
+// Since: 0.2.0
+func (p *Prop) operand(value string) (string, error) {
+	switch p.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("must be an int, got %q", value)
+		}
+		return value, nil
+	case "float":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("must be a float, got %q", value)
+		}
+		return value, nil
+	case "bool":
+		if value != "true" && value != "false" {
+			return "", fmt.Errorf("must be true or false, got %q", value)
+		}
+		return value, nil
+	case "enum":
+		for _, option := range p.Options {
+			if option == value {
+				return strconv.Quote(value), nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s, got %q", p.typeString(), value)
+	}
+
+	return strconv.Quote(value), nil
+}
+
+// Describes the type of a prop as it is declared
+//
+// Receiver:
+// - p (*Prop)
+//
+// Returns:
+// - string
+// ex: "primary"|"secondary"
+//
+// Since: 0.2.0
+func (p *Prop) typeString() string {
+	if p.Type != "enum" {
+		return p.Type
+	}
+
+	options := make([]string, len(p.Options))
+	for i, option := range p.Options {
+		options[i] = strconv.Quote(option)
+	}
+
+	return strings.Join(options, "|")
+}
+
+// Prints a prop declaration in the canonical style
+//
+// Receiver:
+// - p (*Prop)
+//
+// Returns:
+// - string
+// ex: count: int = 0
+//
+// Since: 0.2.0
+func (p *Prop) String() string {
+	s := p.Name + ": " + p.typeString()
+	if !p.HasDefault {
+		return s
+	}
+
+	if p.Type == "string" || p.Type == "enum" {
+		return s + " = " + strconv.Quote(p.Default)
+	}
+
+	return s + " = " + p.Default
+}
+
+// Converts the value of a string prop that contains
+// interpolations to a go template operand
+// ex: Hello {{ name }} -> (print "Hello " .name)
+//
+// Params:
+// - text (string): the attribute value
+// - s (*scope): names bound in the caller
+//
+// Returns:
+// - string: the operand
+// - error: if an interpolation is malformed
+//
+// Since: 0.2.0
+func inlineOperand(text string, s *scope) (string, error) {
+	var parts []string
+
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			break
+		}
+		end := findExpressionEnd(text, start)
+		if end < 0 {
+			break
+		}
+
+		if start > 0 {
+			parts = append(parts, strconv.Quote(text[:start]))
+		}
+		operand, err := translateOperand(text[start+2:end-2], s)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, operand)
+		text = text[end:]
+	}
+
+	if text != "" {
+		parts = append(parts, strconv.Quote(text))
+	}
+
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+
+	return "(print " + strings.Join(parts, " ") + ")", nil
+}
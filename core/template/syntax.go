@@ -1,32 +1,301 @@
 package template
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 )
 
-// Convert lamb syntax to standard go template syntax
+var (
+	identifierRegex = regexp.MustCompile(`^\w+$`)
+	letArgsRegex    = regexp.MustCompile(`^(\w+)\s*=\s*([^=\s].*)$`)
+	forArgsRegex    = regexp.MustCompile(`^(?:(\w+)\s*,\s*)?(\w+)\s+in\s+(\S.*)$`)
+	declareRegex    = regexp.MustCompile(`^(\$\w*(?:\s*,\s*\$\w*)?)\s*(:=|=)([^=].*)?$`)
+	templateRegex   = regexp.MustCompile("^(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)\\s*(.*)$")
+)
+
+// Names bound while rendering, such as component
+// props. Lookups fall back to the parent scope
+//
+// Fields:
+// - parent (*scope): enclosing scope, nil at the top
+// - names (map[string]string): go template operand by lamb name
+// - funcs (map[string]bool): functions registered with the template
+// - dot (string): operand names are read from when not bound,
+// empty to use the parent's
+// ex: $dot
+//
+// Since: 0.2.0
+type scope struct {
+	parent *scope
+	names  map[string]string
+	funcs  map[string]bool
+	dot    string
+}
+
+// Creates a scope
+//
+// Params:
+// - parent (*scope): enclosing scope, may be nil
+//
+// Returns:
+// - *scope
+//
+// Since: 0.2.0
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, names: make(map[string]string)}
+}
+
+// Binds a name to a go template operand
+//
+// Receiver:
+// - s (*scope)
+//
+// Params:
+// - name (string): the lamb name
+// - operand (string): what the name stands for
+// ex: "Hello"
+//
+// Since: 0.2.0
+func (s *scope) set(name string, operand string) {
+	s.names[name] = operand
+}
+
+// Looks a name up in the scope and its parents
+//
+// Receiver:
+// - s (*scope): may be nil
 //
 // Params:
-// - content (string): content to parse
+// - name (string): the lamb name
 //
 // Returns:
-// - string: the parsed content
+// - string: the bound operand
+// - bool: whether the name is bound
 //
-// Since 0.1.0
-func replaceSyntax(content string) string {
-	// Replace variable interpolation
-	content = regexp.MustCompile(`{{\s*(\w+)\s*}}`).ReplaceAllString(content, "{{ .$1 }}")
+// Since: 0.2.0
+func (s *scope) lookup(name string) (string, bool) {
+	for ; s != nil; s = s.parent {
+		if operand, ok := s.names[name]; ok {
+			return operand, true
+		}
+	}
+	return "", false
+}
 
-	// Replace if directives
-	content = regexp.MustCompile(`@if\s+(\w+)`).ReplaceAllString(content, "{{ if .$1 }}")
-	content = regexp.MustCompile(`@elseif\s+(\w+)`).ReplaceAllString(content, "{{ else if .$1 }}")
-	content = regexp.MustCompile(`@else`).ReplaceAllString(content, "{{ else }}")
+// Finds the operand names are read from when
+// they are not bound in the scope
+//
+// Receiver:
+// - s (*scope): may be nil
+//
+// Returns:
+// - string: the operand, . unless the scope renders
+// content passed by the caller of a component
+//
+// Since: 0.2.0
+func (s *scope) lookupDot() string {
+	for ; s != nil; s = s.parent {
+		if s.dot != "" {
+			return s.dot
+		}
+	}
+	return "."
+}
 
-	// Replace for directives
-	content = regexp.MustCompile(`@for\s+(\w+)\s+in\s+(\w+)`).ReplaceAllString(content, "{{ range .$2 }}")
+// Reports whether a name is a go template builtin
+// or a function registered in the scope
+//
+// Receiver:
+// - s (*scope): may be nil
+//
+// Params:
+// - name (string): the name
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (s *scope) isFunc(name string) bool {
+	if builtinFuncs[name] {
+		return true
+	}
+
+	for ; s != nil; s = s.parent {
+		if s.funcs[name] {
+			return true
+		}
+	}
+	return false
+}
 
-	// Replace generic @end
-	content = regexp.MustCompile(`@end`).ReplaceAllString(content, "{{ end }}")
+// Convert a lamb expression to a go template pipeline.
+// Go template actions such as with User have
+// the pipeline after the keyword converted
+//
+// Params:
+// - expr (string): the lamb expression
+// ex: Count > 3 and not User.Admin
+// - s (*scope): names bound while rendering, may be nil
+//
+// Returns:
+// - string: the pipeline
+// ex: and (gt .Count 3) (not .User.Admin)
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func translateExpression(expr string, s *scope) (string, error) {
+	expr = strings.TrimSpace(expr)
+
+	first, rest, _ := strings.Cut(expr, " ")
+	if templateKeywords[first] {
+		return translateAction(first, strings.TrimSpace(rest), s)
+	}
+
+	// Variable declarations keep their names
+	// ex: $x := Count, $i, $x := Items
+	if match := declareRegex.FindStringSubmatch(expr); match != nil {
+		pipeline, err := translatePipeline(match[3], s)
+		if err != nil {
+			return "", err
+		}
+		return match[1] + " " + match[2] + " " + pipeline, nil
+	}
+
+	return translatePipeline(expr, s)
+}
+
+// Convert a go template action, keeping the
+// keyword and template name as they are
+// ex: with User -> with .User
+//
+// Params:
+// - keyword (string): the go template keyword
+// ex: range
+// - rest (string): text after the keyword
+// ex: $i, $u := Users
+// - s (*scope): names bound while rendering, may be nil
+//
+// Returns:
+// - string: the action
+// - error: if the pipeline is malformed
+//
+// Since: 0.2.0
+func translateAction(keyword string, rest string, s *scope) (string, error) {
+	switch keyword {
+	case "if", "with", "range":
+		pipeline, err := translateExpression(rest, s)
+		if err != nil {
+			return "", err
+		}
+		return keyword + " " + pipeline, nil
+	case "else":
+		if rest == "" {
+			return keyword, nil
+		}
+		action, err := translateExpression(rest, s)
+		if err != nil {
+			return "", err
+		}
+		return keyword + " " + action, nil
+	case "template", "block":
+		match := templateRegex.FindStringSubmatch(rest)
+		if match == nil {
+			return "", fmt.Errorf("expected a quoted name after %s", keyword)
+		}
+		if match[2] == "" {
+			return keyword + " " + match[1], nil
+		}
+		pipeline, err := translatePipeline(match[2], s)
+		if err != nil {
+			return "", err
+		}
+		return keyword + " " + match[1] + " " + pipeline, nil
+	}
+
+	return strings.TrimSpace(keyword + " " + rest), nil
+}
+
+// Convert a {{ }} interpolation to go template syntax.
+// Comments and actions that need no translation are
+// kept exactly as written, trim markers are preserved
+//
+// Params:
+// - expr (string): text between the braces
+// - raw (string): full source of the interpolation
+// - s (*scope): names bound while rendering, may be nil
+//
+// Returns:
+// - string: the go template action
+// - error: if the expression is malformed
+//
+// Since: 0.2.0
+func translateInterpolation(expr string, raw string, s *scope) (string, error) {
+	body := strings.TrimSpace(expr)
+	if body == "" || strings.HasPrefix(body, "/*") {
+		return raw, nil
+	}
+
+	open, close := "{{ ", " }}"
+	if strings.HasPrefix(body, "- ") {
+		open = "{{- "
+		body = strings.TrimSpace(body[2:])
+	}
+	if strings.HasSuffix(body, " -") {
+		close = " -}}"
+		body = strings.TrimSpace(body[:len(body)-2])
+	}
+
+	translated, err := translateExpression(body, s)
+	if err != nil {
+		return "", err
+	}
+	if translated == body {
+		return raw, nil
+	}
+
+	return open + translated + close, nil
+}
+
+// Splits the arguments of an @let directive
+//
+// Params:
+// - args (string): directive arguments
+// ex: total = Order.Total
+//
+// Returns:
+// - string: variable name
+// - string: value
+// - bool: whether the arguments are well formed
+//
+// Since: 0.2.0
+func splitLetArgs(args string) (string, string, bool) {
+	match := letArgsRegex.FindStringSubmatch(strings.TrimSpace(args))
+	if match == nil {
+		return "", "", false
+	}
+
+	return match[1], match[2], true
+}
+
+// Splits the arguments of an @for directive
+//
+// Params:
+// - args (string): directive arguments
+// ex: i, user in users
+//
+// Returns:
+// - string: index or key variable, empty when not named
+// - string: loop variable
+// - string: collection
+// - bool: whether the arguments are well formed
+//
+// Since: 0.2.0
+func splitForArgs(args string) (string, string, string, bool) {
+	match := forArgsRegex.FindStringSubmatch(strings.TrimSpace(args))
+	if match == nil {
+		return "", "", "", false
+	}
 
-	return content
+	return match[1], match[2], match[3], true
 }
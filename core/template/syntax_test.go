@@ -5,7 +5,7 @@ import (
 	"testing"
 )
 
-func TestReplaceSyntax(t *testing.T) {
+func TestGenerateSyntax(t *testing.T) {
 	example := `<h1>{{ title }}</h1>
 <p>{{ text }}</p>
 @if LoggedIn
@@ -28,11 +28,14 @@ func TestReplaceSyntax(t *testing.T) {
 {{ else }}
 <p>Welcome</p>
 {{ end }}
-{{ range .users }}
+{{ range $user := .users }}
 <p>{{ .username }}</p>
 {{ end }}`
 
-	result := replaceSyntax(example)
+	result, err := generateTestSource(t, example)
+	if err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
 
 	if !reflect.DeepEqual(result, expected) {
 		t.Errorf("Expected %v, but got %v", expected, result)
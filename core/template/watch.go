@@ -0,0 +1,412 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Recompiles pages when lamb files change. Changes are
+// detected by polling modification times, and only the
+// pages that include a changed file are recompiled
+//
+// Fields:
+// - SrcDir (string): directory of pages to compile
+// - PageFS (fs.FS): file system SrcDir, LayoutDir and PartialDir
+// are read from, the disk is used when nil
+// - LayoutDir (string): directory @extends resolves layouts from,
+// defaults to SrcDir
+// - PartialDir (string): directory @include resolves partials from,
+// defaults to SrcDir
+// - ComponentDir (string): directory containing components
+// - ComponentPrefixes ([]string): tag prefixes of the
+// components, defaults to ui-
+// - ComponentRoots ([]ComponentRoot): more places components are
+// searched, only the ones on disk are watched
+// - AllowUnknownComponents (bool): leave components without a file
+// in the output as custom elements instead of failing
+// - OutputDir (string): directory to write to, defaults to .cache
+// - Interval (time.Duration): time between polls, defaults to 500ms
+// - OnCompile (func(string, error)): called after each page is compiled
+// - files (map[string]fileState): last seen state of every lamb file
+// - dependencies (map[string]map[string]bool): files each page was built from
+//
+// Since: 0.2.0
+type Watcher struct {
+	SrcDir                 string
+	PageFS                 fs.FS
+	LayoutDir              string
+	PartialDir             string
+	ComponentDir           string
+	ComponentPrefixes      []string
+	ComponentRoots         []ComponentRoot
+	AllowUnknownComponents bool
+	OutputDir              string
+	Interval               time.Duration
+	OnCompile              func(page string, err error)
+	files                  map[string]fileState
+	dependencies           map[string]map[string]bool
+}
+
+// Last seen state of a watched file
+//
+// Fields:
+// - modTime (time.Time): modification time
+// - size (int64): size in bytes
+//
+// Since: 0.2.0
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Compiles every page and watches for changes
+// until the context is cancelled
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Params:
+// - ctx (context.Context): stops the watcher when done
+//
+// Returns:
+// - error: the context error once stopped
+//
+// Since: 0.2.0
+func (w *Watcher) Watch(ctx context.Context) error {
+	// Compile errors are reported through OnCompile
+	_ = w.Build()
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_ = w.Poll()
+		}
+	}
+}
+
+// Compiles every page and records its dependencies
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Returns:
+// - error: every failing page joined with errors.Join
+//
+// Since: 0.2.0
+func (w *Watcher) Build() error {
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+
+	w.files = files
+	w.dependencies = make(map[string]map[string]bool)
+
+	var errs []error
+	for file := range files {
+		if w.isPage(file) {
+			errs = append(errs, w.compile(file))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Checks for changed files once and recompiles the
+// pages affected by them. Outputs of deleted pages
+// are removed
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Returns:
+// - error: every failing page joined with errors.Join
+//
+// Since: 0.2.0
+func (w *Watcher) Poll() error {
+	if w.files == nil {
+		return w.Build()
+	}
+
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]bool)
+	for file, state := range files {
+		if previous, ok := w.files[file]; !ok || previous != state {
+			changed[file] = true
+		}
+	}
+	for file := range w.files {
+		if _, ok := files[file]; !ok {
+			changed[file] = true
+		}
+	}
+	w.files = files
+
+	var errs []error
+	for file := range changed {
+		if _, exists := files[file]; !exists && w.isPage(file) {
+			delete(w.dependencies, file)
+			errs = append(errs, w.removeOutput(file))
+		}
+	}
+
+	for file := range files {
+		if !w.isPage(file) {
+			continue
+		}
+
+		affected := changed[file]
+		for dependency := range w.dependencies[file] {
+			affected = affected || changed[dependency]
+		}
+
+		if affected {
+			errs = append(errs, w.compile(file))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Compiles a page and records its dependencies
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Params:
+// - page (string): path to the page
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func (w *Watcher) compile(page string) error {
+	compiler := w.compiler(page)
+	components, err := compiler.compile()
+
+	dependencies := make(map[string]bool)
+	for _, component := range components {
+		dependencies[filepath.Clean(component)] = true
+	}
+	w.dependencies[page] = dependencies
+
+	if w.OnCompile != nil {
+		w.OnCompile(page, err)
+	}
+
+	return err
+}
+
+// Removes the output of a deleted page
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Params:
+// - page (string): path to the page
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func (w *Watcher) removeOutput(page string) error {
+	compiler := w.compiler(page)
+	output := compiler.getOutputFilePath(compiler.getOutputRelPath(), compiler.getOutputDir())
+
+	err := os.Remove(output)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Creates the compiler for a page
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Params:
+// - page (string): path to the page
+//
+// Returns:
+// - *Compiler
+//
+// Since: 0.2.0
+func (w *Watcher) compiler(page string) *Compiler {
+	return &Compiler{
+		ComponentDir:           w.ComponentDir,
+		ComponentPrefixes:      w.ComponentPrefixes,
+		ComponentRoots:         w.ComponentRoots,
+		AllowUnknownComponents: w.AllowUnknownComponents,
+		PageFS:                 w.PageFS,
+		FilePath:               page,
+		SourceDir:              w.SrcDir,
+		LayoutDir:              w.LayoutDir,
+		PartialDir:             w.PartialDir,
+		OutputDir:              w.OutputDir,
+	}
+}
+
+// Reports whether a lamb file is a page: a file of
+// the source directory outside of the component directories
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Params:
+// - file (string): path to the file
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func (w *Watcher) isPage(file string) bool {
+	if !isInsideDir(file, w.SrcDir) {
+		return false
+	}
+
+	for _, dir := range w.componentDirs() {
+		if isInsideDir(file, dir) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Lists the component directories on disk
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Returns:
+// - []string
+//
+// Since: 0.2.0
+func (w *Watcher) componentDirs() []string {
+	var dirs []string
+	if w.ComponentDir != "" {
+		dirs = append(dirs, w.ComponentDir)
+	}
+
+	// Roots in a file system, such as an embed.FS, do not change
+	for _, root := range w.ComponentRoots {
+		if root.FS == nil && root.Dir != "" {
+			dirs = append(dirs, root.Dir)
+		}
+	}
+
+	return dirs
+}
+
+// Reports whether a path is inside a directory
+//
+// Params:
+// - file (string): the path
+// - dir (string): the directory
+//
+// Returns:
+// - bool
+//
+// Since: 0.2.0
+func isInsideDir(file string, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(file))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Collects the state of every lamb file in the
+// source, layout, partial and component directories
+//
+// Receiver:
+// - w (*Watcher)
+//
+// Returns:
+// - map[string]fileState: state by cleaned path
+// - error
+//
+// Since: 0.2.0
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+
+	err := scanLambFiles(files, w.PageFS, w.SrcDir, w.LayoutDir, w.PartialDir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanLambFiles(files, nil, w.componentDirs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Records the state of every lamb file in directories
+//
+// Params:
+// - files (map[string]fileState): state by cleaned path, filled in
+// - fsys (fs.FS): file system to read from, nil for the disk
+// - dirs (...string): directories to walk, empty ones are skipped
+//
+// Returns:
+// - error
+//
+// Since: 0.2.0
+func scanLambFiles(files map[string]fileState, fsys fs.FS, dirs ...string) error {
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".lamb.html") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[filepath.Clean(path)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		var err error
+		if fsys != nil {
+			err = fs.WalkDir(fsys, dir, walk)
+		} else {
+			err = filepath.WalkDir(dir, walk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
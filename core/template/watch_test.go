@@ -0,0 +1,201 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWatcherRecompilesAffectedPages(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/home.lamb.html":              "<ui-card />",
+		"views/about.lamb.html":             "<ui-title />",
+		"views/components/card.lamb.html":   "<div><ui-title /></div>",
+		"views/components/title.lamb.html":  "<h1>Title</h1>",
+		"views/components/footer.lamb.html": "<footer></footer>",
+	})
+
+	var compiled []string
+	watcher := &Watcher{
+		SrcDir:       filepath.Join(dir, "views"),
+		ComponentDir: filepath.Join(dir, "views", "components"),
+		OutputDir:    filepath.Join(dir, "out"),
+		OnCompile: func(page string, err error) {
+			compiled = append(compiled, filepath.Base(page))
+		},
+	}
+
+	if err := watcher.Build(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	touch := func(name string, content string) {
+		path := filepath.Join(dir, "views", name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	examples := []struct {
+		file     string
+		content  string
+		expected []string
+	}{
+		{"components/footer.lamb.html", "<footer>new</footer>", nil},
+		{"components/card.lamb.html", "<section><ui-title /></section>", []string{"home.lamb.html"}},
+		{"components/title.lamb.html", "<h1>New</h1>", []string{"about.lamb.html", "home.lamb.html"}},
+		{"about.lamb.html", "<p>About</p>", []string{"about.lamb.html"}},
+	}
+
+	for _, example := range examples {
+		compiled = nil
+		touch(example.file, example.content)
+
+		if err := watcher.Poll(); err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		sort.Strings(compiled)
+		if !reflect.DeepEqual(compiled, example.expected) {
+			t.Errorf("Expected %v to be recompiled after changing %s, but got %v", example.expected, example.file, compiled)
+		}
+	}
+
+	result, _ := os.ReadFile(filepath.Join(dir, "out", "home.html"))
+	if string(result) != "<section><h1>New</h1></section>" {
+		t.Errorf("Expected the output to be updated, but got %s", string(result))
+	}
+}
+
+func TestWatcherRecoversFromMissingComponent(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/home.lamb.html": "<ui-card />",
+	})
+
+	watcher := &Watcher{
+		SrcDir:       filepath.Join(dir, "views"),
+		ComponentDir: filepath.Join(dir, "views", "components"),
+		OutputDir:    filepath.Join(dir, "out"),
+	}
+
+	if err := watcher.Build(); err == nil {
+		t.Fatalf("Expected an error, but got none")
+	}
+
+	writeLambFiles(t, dir, map[string]string{
+		"views/components/card.lamb.html": "<div>card</div>",
+	})
+
+	if err := watcher.Poll(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "out", "home.html")); err != nil {
+		t.Errorf("Expected home.html to be compiled once the component exists")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "views", "home.lamb.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Poll(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "out", "home.html")); !os.IsNotExist(err) {
+		t.Errorf("Expected home.html to be removed with its page")
+	}
+}
+
+func TestWatcherLayoutPartialAndComponentRoots(t *testing.T) {
+	dir := t.TempDir()
+	writeLambFiles(t, dir, map[string]string{
+		"views/home.lamb.html":              "@extends(\"base\")\n@section(\"content\")<acme-badge />@end",
+		"views/about.lamb.html":             "@include(\"note\")",
+		"layouts/base.lamb.html":            "<main>@yield(\"content\")</main>",
+		"partials/note.lamb.html":           "<p>Note</p>",
+		"shared/components/badge.lamb.html": "<b>Badge</b>",
+	})
+
+	var compiled []string
+	watcher := &Watcher{
+		SrcDir:         filepath.Join(dir, "views"),
+		LayoutDir:      filepath.Join(dir, "layouts"),
+		PartialDir:     filepath.Join(dir, "partials"),
+		ComponentRoots: []ComponentRoot{{Prefix: "acme-", Dir: filepath.Join(dir, "shared", "components")}},
+		OutputDir:      filepath.Join(dir, "out"),
+		OnCompile: func(page string, err error) {
+			compiled = append(compiled, filepath.Base(page))
+		},
+	}
+
+	if err := watcher.Build(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	examples := []struct {
+		file     string
+		content  string
+		expected []string
+	}{
+		{"layouts/base.lamb.html", "<div>@yield(\"content\")</div>", []string{"home.lamb.html"}},
+		{"partials/note.lamb.html", "<p>New</p>", []string{"about.lamb.html"}},
+		{"shared/components/badge.lamb.html", "<b>New</b>", []string{"home.lamb.html"}},
+	}
+
+	for _, example := range examples {
+		compiled = nil
+		path := filepath.Join(dir, example.file)
+		if err := os.WriteFile(path, []byte(example.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := watcher.Poll(); err != nil {
+			t.Fatalf("Expected no error, but got error: %s", err.Error())
+		}
+
+		if !reflect.DeepEqual(compiled, example.expected) {
+			t.Errorf("Expected %v to be recompiled after changing %s, but got %v", example.expected, example.file, compiled)
+		}
+	}
+
+	result, _ := os.ReadFile(filepath.Join(dir, "out", "home.html"))
+	if string(result) != "<div><b>New</b></div>" {
+		t.Errorf("Expected the output to be updated, but got %s", string(result))
+	}
+}
+
+func TestWatcherPageFS(t *testing.T) {
+	pages := fstest.MapFS{
+		"views/home.lamb.html": {Data: []byte("<p>Home</p>"), ModTime: time.Now()},
+	}
+	out := t.TempDir()
+
+	watcher := &Watcher{PageFS: pages, SrcDir: "views", OutputDir: out}
+	if err := watcher.Build(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	pages["views/home.lamb.html"] = &fstest.MapFile{Data: []byte("<p>New</p>"), ModTime: time.Now().Add(time.Minute)}
+	if err := watcher.Poll(); err != nil {
+		t.Fatalf("Expected no error, but got error: %s", err.Error())
+	}
+
+	result, _ := os.ReadFile(filepath.Join(out, "home.html"))
+	if string(result) != "<p>New</p>" {
+		t.Errorf("Expected the output to be updated, but got %s", string(result))
+	}
+}